@@ -0,0 +1,194 @@
+// Package app runs the server's lifecycle: background workers, readiness,
+// and an orderly shutdown on SIGINT/SIGTERM.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// App owns the process lifecycle. Workers started with Go run on Context,
+// which is cancelled when shutdown begins; shutdown then waits for them, up
+// to ShutdownTimeout in total, before closing resources in reverse order.
+type App struct {
+	// Total time allowed for draining HTTP requests and workers and closing resources
+	ShutdownTimeout time.Duration
+	// How long readiness reports false before the server stops taking
+	// requests, so load balancers can route away first
+	ReadinessDelay time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	ready  atomic.Bool
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	checks  []check
+	closers []closer
+}
+
+type check struct {
+	name string
+	fn   func(context.Context) error
+}
+
+type closer struct {
+	name string
+	fn   func(context.Context) error
+}
+
+func New(shutdownTimeout, readinessDelay time.Duration) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &App{
+		ShutdownTimeout: shutdownTimeout,
+		ReadinessDelay:  readinessDelay,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Context is cancelled as soon as shutdown begins. Background loops should
+// stop taking new work when it is done.
+func (a *App) Context() context.Context { return a.ctx }
+
+// Go runs a worker that shutdown waits for. fn should return soon after ctx
+// is done, finishing whatever it is in the middle of first.
+func (a *App) Go(name string, fn func(ctx context.Context)) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("app: worker %s panicked: %v", name, p)
+			}
+		}()
+		fn(a.ctx)
+	}()
+}
+
+// Workers runs n copies of a worker
+func (a *App) Workers(name string, n int, fn func(ctx context.Context)) {
+	for i := range max(n, 1) {
+		a.Go(fmt.Sprintf("%s-%d", name, i+1), fn)
+	}
+}
+
+// AddCheck adds a dependency that has to answer for the app to be ready
+func (a *App) AddCheck(name string, fn func(context.Context) error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.checks = append(a.checks, check{name, fn})
+}
+
+// OnShutdown registers a resource to close once HTTP and the workers have
+// drained. Resources close in the reverse order they were registered.
+func (a *App) OnShutdown(name string, fn func(context.Context) error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closers = append(a.closers, closer{name, fn})
+}
+
+// Live always answers 200 while the process is up
+func (a *App) Live(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// Ready answers 200 while the app is serving and its dependencies respond,
+// and 503 once shutdown has begun
+func (a *App) Ready(w http.ResponseWriter, r *http.Request) {
+	if !a.ready.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	a.mu.Lock()
+	checks := a.checks
+	a.mu.Unlock()
+	for _, c := range checks {
+		if err := c.fn(ctx); err != nil {
+			http.Error(w, fmt.Sprintf("%s: %v", c.name, err), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// Run serves HTTP until SIGINT or SIGTERM, then shuts down: readiness goes
+// false, the server stops accepting and drains requests, workers are
+// cancelled and waited for, and resources are closed. Everything after the
+// readiness delay shares one ShutdownTimeout deadline.
+func (a *App) Run(srv *http.Server) error {
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println(" Listening on " + srv.Addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+	a.ready.Store(true)
+
+	var errs []error
+	select {
+	case <-sigCtx.Done():
+		log.Println("app: shutting down")
+	case err := <-serveErr:
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+	stop() // a second signal kills the process the usual way
+
+	a.ready.Store(false)
+	if len(errs) == 0 && a.ReadinessDelay > 0 {
+		time.Sleep(a.ReadinessDelay)
+	}
+
+	deadline, cancel := context.WithTimeout(context.Background(), a.ShutdownTimeout)
+	defer cancel()
+
+	// Workers stop taking new jobs, and long-lived streams end, while the
+	// server drains the requests already in flight
+	a.cancel()
+	if err := srv.Shutdown(deadline); err != nil {
+		errs = append(errs, fmt.Errorf("http drain: %w", err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-deadline.Done():
+		errs = append(errs, errors.New("workers still running at the shutdown deadline"))
+	}
+
+	a.mu.Lock()
+	closers := a.closers
+	a.mu.Unlock()
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].fn(deadline); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", closers[i].name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	log.Println("app: shut down cleanly")
+	return nil
+}
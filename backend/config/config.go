@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,56 @@ type Config struct {
 	Port        string
 	DatabaseURL string
 	RedisURL    string
+
+	// Image uploads
+	PublicBaseURL  string
+	StorageDriver  string // "local" or "s3"
+	StorageDir     string
+	MaxUploadBytes int64
+	S3Endpoint     string
+	S3Bucket       string
+	S3Region       string
+	S3AccessKey    string
+	S3SecretKey    string
+
+	// Inventory
+	ReservationTTL time.Duration
+
+	// How long checkout/payment responses are replayed for a repeated key
+	IdempotencyTTL time.Duration
+
+	// Most units of one product a cart may hold
+	MaxCartQuantity int
+
+	// Key that signs guest sessions; JWT_SECRET when GUEST_SESSION_SECRET is unset
+	GuestSessionSecret string
+
+	// How a guest cart merges into the user's on login: sum, max, guest or user
+	CartMergeStrategy string
+
+	// Paid orders the restaurant hasn't accepted within this are auto-rejected
+	OrderAcceptWindow time.Duration
+
+	// Percent of the payment refunded when a customer cancels after the
+	// restaurant accepted (before that it's always 100, after pickup 0)
+	CancelRefundPercent int
+
+	// Order queue: "postgres" (default), "kafka" or "memory"
+	QueueDriver  string
+	KafkaBrokers []string
+	KafkaTopic   string
+	KafkaGroupID string
+
+	// Order queue: attempts before a job goes to dead_jobs, and how long a
+	// worker has to finish a job before another may take it
+	QueueMaxAttempts       int
+	QueueVisibilityTimeout time.Duration
+	QueueWorkers           int // jobs handled at once by this instance
+
+	// Graceful shutdown: readiness reports false for ReadinessDelay, then
+	// requests, jobs and connections get ShutdownTimeout to wind down
+	ShutdownTimeout time.Duration
+	ReadinessDelay  time.Duration
 }
 
 func Load() *Config {
@@ -20,10 +73,56 @@ func Load() *Config {
 	dbURL := mustGet("DATABASE_URL")
 	redisURL := mustGet("REDIS_URL")
 
+	guestSecret := getOrDefault("GUEST_SESSION_SECRET", os.Getenv("JWT_SECRET"))
+	if guestSecret == "" {
+		log.Fatal("Missing required env var: GUEST_SESSION_SECRET or JWT_SECRET")
+	}
+
+	cancelRefundPercent := getIntOrDefault("CANCEL_REFUND_PERCENT", 50)
+	if cancelRefundPercent < 0 || cancelRefundPercent > 100 {
+		log.Fatalf("Invalid value for env var CANCEL_REFUND_PERCENT: %d is not between 0 and 100", cancelRefundPercent)
+	}
+
 	return &Config{
 		Port:        port,
 		DatabaseURL: dbURL,
 		RedisURL:    redisURL,
+
+		PublicBaseURL:  getOrDefault("PUBLIC_BASE_URL", "http://localhost:"+port),
+		StorageDriver:  getOrDefault("STORAGE_DRIVER", "local"),
+		StorageDir:     getOrDefault("STORAGE_DIR", "./uploads"),
+		MaxUploadBytes: int64(getIntOrDefault("MAX_UPLOAD_BYTES", 5<<20)),
+		S3Endpoint:     os.Getenv("S3_ENDPOINT"),
+		S3Bucket:       os.Getenv("S3_BUCKET"),
+		S3Region:       getOrDefault("S3_REGION", "us-east-1"),
+		S3AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:    os.Getenv("S3_SECRET_KEY"),
+
+		ReservationTTL: time.Duration(getIntOrDefault("RESERVATION_TTL_MINUTES", 15)) * time.Minute,
+
+		IdempotencyTTL: time.Duration(getIntOrDefault("IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour,
+
+		MaxCartQuantity: getIntOrDefault("CART_MAX_QUANTITY", 20),
+
+		GuestSessionSecret: guestSecret,
+
+		CartMergeStrategy: getOrDefault("CART_MERGE_STRATEGY", "sum"),
+
+		OrderAcceptWindow: time.Duration(getIntOrDefault("ORDER_ACCEPT_TIMEOUT_MINUTES", 10)) * time.Minute,
+
+		CancelRefundPercent: cancelRefundPercent,
+
+		QueueDriver:  getOrDefault("QUEUE_DRIVER", "postgres"),
+		KafkaBrokers: strings.Split(getOrDefault("KAFKA_BROKERS", "localhost:9092"), ","),
+		KafkaTopic:   getOrDefault("KAFKA_TOPIC", "orders"),
+		KafkaGroupID: getOrDefault("KAFKA_GROUP_ID", "order-workers"),
+
+		QueueMaxAttempts:       getIntOrDefault("QUEUE_MAX_ATTEMPTS", 8),
+		QueueVisibilityTimeout: time.Duration(getIntOrDefault("QUEUE_VISIBILITY_TIMEOUT_SECONDS", 60)) * time.Second,
+		QueueWorkers:           getIntOrDefault("QUEUE_WORKERS", 4),
+
+		ShutdownTimeout: time.Duration(getIntOrDefault("SHUTDOWN_TIMEOUT_SECONDS", 25)) * time.Second,
+		ReadinessDelay:  time.Duration(getIntOrDefault("SHUTDOWN_READINESS_DELAY_SECONDS", 5)) * time.Second,
 	}
 }
 
@@ -34,6 +133,18 @@ func getOrDefault(key, fallback string) string {
 	return fallback
 }
 
+func getIntOrDefault(key string, fallback int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Fatalf("Invalid integer for env var %s: %v", key, err)
+	}
+	return n
+}
+
 func mustGet(key string) string {
 	val := os.Getenv(key)
 	if val == "" {
@@ -1,14 +1,26 @@
 package db
 
 import (
+	"fmt"
 	"log"
 
+	"swiggy-clone/backend/kafka"
 	"swiggy-clone/backend/models"
 
 	"gorm.io/gorm"
 )
 
 func AutoMigrate(gdb *gorm.DB) {
+	if err := migrateMoneyColumns(gdb); err != nil {
+		log.Fatalf("money migration failed: %v", err)
+	}
+	if err := dedupeOrderIdempotencyKeys(gdb); err != nil {
+		log.Fatalf("order idempotency key migration failed: %v", err)
+	}
+
+	// Checked before AutoMigrate adds the column
+	backfillSoldOut := !gdb.Migrator().HasColumn(&models.Product{}, "sold_out")
+
 	err := gdb.AutoMigrate(
 		&models.User{},
 		&models.Product{},
@@ -16,8 +28,206 @@ func AutoMigrate(gdb *gorm.DB) {
 		&models.OrderItem{},
 		&models.CartItem{},
 		&models.Payment{}, // ✅ add this line
+		&models.ImageAsset{},
+		&models.InventoryMovement{},
+		&models.StockReservation{},
+		&models.Notification{},
+		&models.ProductPrice{},
+		&models.Restaurant{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.SavedItem{},
+		&models.Favorite{},
+		&models.OrderStatusHistory{},
+		&models.QueueJob{},
+		&models.DeadJob{},
+		&models.OutboxEvent{},
 	)
 	if err != nil {
 		log.Fatalf("migration failed: %v", err)
 	}
+
+	if backfillSoldOut {
+		if err := markSoldOutProducts(gdb); err != nil {
+			log.Fatalf("sold out backfill failed: %v", err)
+		}
+	}
+	if err := migrateOrderStatuses(gdb); err != nil {
+		log.Fatalf("order status migration failed: %v", err)
+	}
+	if err := backfillOrderHistory(gdb); err != nil {
+		log.Fatalf("order history backfill failed: %v", err)
+	}
+	if err := backfillOutboxPayloads(gdb); err != nil {
+		log.Fatalf("outbox payload backfill failed: %v", err)
+	}
+}
+
+// markSoldOutProducts runs once, when the sold_out column is added. Until
+// then availability was switched off only when stock ran out, so every
+// unavailable product without stock was switched off that way.
+func markSoldOutProducts(gdb *gorm.DB) error {
+	res := gdb.Model(&models.Product{}).
+		Where("stock = 0 AND NOT is_available").
+		Update("sold_out", true)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("migrate: marked %d products as sold out", res.RowsAffected)
+	}
+	return nil
+}
+
+// backfillOutboxPayloads fills in the payload of outbox rows written before
+// the outbox stored whole events, from their columns. Running it again is a
+// no-op.
+func backfillOutboxPayloads(gdb *gorm.DB) error {
+	var rows []models.OutboxEvent
+	if err := gdb.Where("payload IS NULL").Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		ev, err := kafka.StoredEvent(nil, row.EventID, row.EventType, row.OrderID, row.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("outbox row %d: %w", row.ID, err)
+		}
+		payload, err := ev.Encode()
+		if err != nil {
+			return err
+		}
+		if err := gdb.Model(&row).Update("payload", payload).Error; err != nil {
+			return err
+		}
+	}
+	if len(rows) > 0 {
+		log.Printf("migrate: filled in the payload of %d outbox events", len(rows))
+	}
+	return nil
+}
+
+// dedupeOrderIdempotencyKeys clears repeated idempotency keys so AutoMigrate
+// can create the unique index on (user_id, idempotency_key). Orders written
+// before the index existed could share a key; the oldest order keeps it.
+// Running it again is a no-op.
+func dedupeOrderIdempotencyKeys(gdb *gorm.DB) error {
+	if !gdb.Migrator().HasColumn(&models.Order{}, "idempotency_key") {
+		return nil
+	}
+	res := gdb.Exec(`UPDATE orders SET idempotency_key = NULL
+		WHERE idempotency_key IS NOT NULL AND id NOT IN (
+			SELECT MIN(id) FROM orders WHERE idempotency_key IS NOT NULL
+			GROUP BY user_id, idempotency_key
+		)`)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("migrate: cleared %d duplicate order idempotency keys", res.RowsAffected)
+	}
+	return nil
+}
+
+// migrateOrderStatuses maps statuses from before the order lifecycle onto it.
+// FAILED orders were unpaid orders whose stock was released, so they count as
+// cancelled. The old status is kept in legacy_status, which is how the
+// auto-reject tells these orders apart from ones placed under the lifecycle.
+// Running it again is a no-op.
+func migrateOrderStatuses(gdb *gorm.DB) error {
+	res := gdb.Exec(`UPDATE orders SET legacy_status = status, status = CASE status
+		WHEN 'PENDING' THEN 'PLACED'
+		WHEN 'PROCESSING' THEN 'ACCEPTED'
+		WHEN 'SUCCESS' THEN 'DELIVERED'
+		WHEN 'FAILED' THEN 'CANCELLED'
+		END
+		WHERE status IN ('PENDING', 'PROCESSING', 'SUCCESS', 'FAILED')`)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("migrate: moved %d orders to the new lifecycle statuses", res.RowsAffected)
+	}
+	return nil
+}
+
+// backfillOrderHistory gives orders from before the status history their
+// entries: the initial PLACED at the time the order was placed and, if the
+// order has moved on since, one step to where it is now, at its last update.
+// Both are recorded as the system's. Orders that already have history are
+// left alone, so running it again is a no-op.
+func backfillOrderHistory(gdb *gorm.DB) error {
+	var ids []uint
+	if err := gdb.Raw(`SELECT id FROM orders o
+		WHERE NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_id = o.id)
+		ORDER BY id`).Scan(&ids).Error; err != nil {
+		return err
+	}
+	for start := 0; start < len(ids); start += 1000 {
+		batch := ids[start:min(start+1000, len(ids))]
+		err := gdb.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(`INSERT INTO order_status_history
+				(order_id, from_status, to_status, actor_type, reason, created_at)
+				SELECT id, '', ?, ?, 'order placed (recorded by migration)', COALESCE(placed_at, created_at)
+				FROM orders WHERE id IN ? ORDER BY id`,
+				models.OrderPlaced, models.ActorSystem, batch).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`INSERT INTO order_status_history
+				(order_id, from_status, to_status, actor_type, reason, created_at)
+				SELECT id, ?, status, ?,
+					'status before the history existed' || COALESCE(' (was ' || legacy_status || ')', ''),
+					GREATEST(updated_at, COALESCE(placed_at, created_at))
+				FROM orders WHERE id IN ? AND status <> ? ORDER BY id`,
+				models.OrderPlaced, models.ActorSystem, batch, models.OrderPlaced).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("migrate: backfilled the status history of %d orders", len(ids))
+	}
+	return nil
+}
+
+// moneyColumns used to be float columns holding rupees; they now hold
+// bigint paise (see models.Money)
+var moneyColumns = []struct{ table, column string }{
+	{"products", "price"},
+	{"orders", "total"},
+	{"order_items", "price_at_purchase"},
+	{"payments", "amount"},
+	{"product_prices", "price"},
+}
+
+// migrateMoneyColumns converts the old float columns to minor units in place.
+// It runs before AutoMigrate, which would otherwise cast 249.5 to 250.
+// Columns that are already bigint (or don't exist yet) are left alone.
+func migrateMoneyColumns(gdb *gorm.DB) error {
+	return gdb.Transaction(func(tx *gorm.DB) error {
+		for _, c := range moneyColumns {
+			var dataType string
+			err := tx.Raw(
+				`SELECT data_type FROM information_schema.columns
+				 WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+				c.table, c.column,
+			).Scan(&dataType).Error
+			if err != nil {
+				return err
+			}
+			if dataType != "double precision" && dataType != "real" && dataType != "numeric" {
+				continue
+			}
+
+			log.Printf("migrate: converting %s.%s to minor units", c.table, c.column)
+			stmt := fmt.Sprintf(
+				`ALTER TABLE %q ALTER COLUMN %q TYPE bigint USING ROUND(%q * 100)::bigint`,
+				c.table, c.column, c.column,
+			)
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("%s.%s: %w", c.table, c.column, err)
+			}
+		}
+		return nil
+	})
 }
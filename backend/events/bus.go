@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"swiggy-clone/backend/kafka"
+)
+
+// Outbox stores encoded events in the publisher's transaction and relays
+// them to the queue once committed
+type Outbox interface {
+	Add(tx *gorm.DB, ev kafka.Event) error
+	Wake()
+}
+
+// Bus publishes typed events through the outbox onto whichever queue is
+// configured (in-memory, Postgres or Kafka) and fans each delivered event out
+// to its subscribers.
+//
+// Delivery is at least once: if any subscriber fails, the queue retries the
+// event and every subscriber sees it again, so handlers must tolerate repeats
+// (EventID tells them which event they are handling).
+type Bus struct {
+	outbox Outbox
+	orders OrderLoader // completes events from before their current version
+
+	mu   sync.RWMutex
+	subs map[string][]*subscription
+}
+
+type subscription struct {
+	name   string
+	slots  chan struct{} // limits how many events the subscriber handles at once
+	handle func(context.Context, Event) error
+}
+
+func NewBus(outbox Outbox, orders OrderLoader) *Bus {
+	return &Bus{outbox: outbox, orders: orders, subs: map[string][]*subscription{}}
+}
+
+// Publish records ev in tx. It reaches subscribers only if tx commits. A nil
+// bus drops the event, for services wired up without one.
+func (b *Bus) Publish(tx *gorm.DB, ev Event) error {
+	if b == nil {
+		return nil
+	}
+	raw, err := Encode(ev)
+	if err != nil {
+		return err
+	}
+	return b.outbox.Add(tx, raw)
+}
+
+// Wake asks the relay to deliver what was just committed, instead of waiting
+// for its next poll
+func (b *Bus) Wake() {
+	if b != nil {
+		b.outbox.Wake()
+	}
+}
+
+// Subscribe registers handle for events of type E. The handler runs at most
+// concurrency times at once; name identifies it in logs and errors.
+func Subscribe[E Event](b *Bus, name string, concurrency int, handle func(context.Context, E) error) {
+	var zero E
+	if _, ok := codecs[zero.Type()]; !ok {
+		panic(fmt.Sprintf("events: subscribe %s to unregistered type %q", name, zero.Type()))
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[zero.Type()] = append(b.subs[zero.Type()], &subscription{
+		name:  name,
+		slots: make(chan struct{}, concurrency),
+		handle: func(ctx context.Context, ev Event) error {
+			return handle(ctx, ev.(E))
+		},
+	})
+}
+
+// Dispatch is the queue worker's handler: it decodes the event and runs every
+// subscriber for its type side by side, returning their failures joined
+func (b *Bus) Dispatch(ctx context.Context, raw kafka.Event) error {
+	ev, err := Decode(ctx, raw, b.orders)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, eventIDKey{}, raw.ID)
+	b.mu.RLock()
+	subs := b.subs[ev.Type()]
+	b.mu.RUnlock()
+
+	errs := make([]error, len(subs))
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sub.run(ctx, ev); err != nil {
+				log.Printf("events: %s failed on %s %s: %v", sub.name, raw.Type, raw.ID, err)
+				errs[i] = fmt.Errorf("%s: %w", sub.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+type eventIDKey struct{}
+
+// EventID is the ID of the event a subscriber is handling. It is the same on
+// every redelivery, so handlers with side effects can use it to skip repeats.
+func EventID(ctx context.Context) string {
+	id, _ := ctx.Value(eventIDKey{}).(string)
+	return id
+}
+
+func (s *subscription) run(ctx context.Context, ev Event) (err error) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+	return s.handle(ctx, ev)
+}
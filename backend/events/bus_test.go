@@ -0,0 +1,239 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"swiggy-clone/backend/kafka"
+)
+
+func mustEncode(t *testing.T, ev Event) kafka.Event {
+	t.Helper()
+	raw, err := Encode(ev)
+	if err != nil {
+		t.Fatalf("encode %s: %v", ev.Type(), err)
+	}
+	return raw
+}
+
+func TestSubscribeConcurrencyLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		want        int32
+	}{
+		{"one at a time", 1, 1},
+		{"three at once", 3, 3},
+		{"zero means one", 0, 1},
+		{"negative means one", -2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const events = 8
+			bus := NewBus(nil, nil)
+			var running, peak, handled atomic.Int32
+			Subscribe(bus, "slow", tt.concurrency, func(context.Context, StockLow) error {
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				running.Add(-1)
+				handled.Add(1)
+				return nil
+			})
+
+			var wg sync.WaitGroup
+			for i := range events {
+				raw := mustEncode(t, StockLow{ProductID: uint(i + 1)})
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := bus.Dispatch(context.Background(), raw); err != nil {
+						t.Errorf("dispatch: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := handled.Load(); got != events {
+				t.Errorf("handled %d events, want %d", got, events)
+			}
+			if got := peak.Load(); got != tt.want {
+				t.Errorf("at most %d handlers ran at once, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeWaitsForSlotUntilCancelled(t *testing.T) {
+	bus := NewBus(nil, nil)
+	release := make(chan struct{})
+	Subscribe(bus, "busy", 1, func(context.Context, StockLow) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	first := make(chan error, 1)
+	go func() { first <- bus.Dispatch(context.Background(), mustEncode(t, StockLow{ProductID: 1})) }()
+	// let the first event take the only slot
+	for len(bus.subs[TypeStockLow][0].slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := bus.Dispatch(ctx, mustEncode(t, StockLow{ProductID: 2}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("dispatch while busy = %v, want deadline exceeded", err)
+	}
+}
+
+func TestSubscribeUnregisteredTypePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("subscribing to an unregistered type did not panic")
+		}
+	}()
+	Subscribe(NewBus(nil, nil), "nobody", 1, func(context.Context, unregistered) error { return nil })
+}
+
+type unregistered struct{}
+
+func (unregistered) Type() string { return "test.unregistered" }
+func (unregistered) Key() string  { return "" }
+
+func TestDispatchJoinsSubscriberErrors(t *testing.T) {
+	errStock := errors.New("stock service down")
+	errMail := errors.New("mail server down")
+
+	tests := []struct {
+		name     string
+		handlers map[string]func(context.Context, StockLow) error
+		wantIs   []error
+		wantIn   []string // subscriber names the error must mention
+		wantOK   bool
+	}{
+		{
+			name:   "no subscribers",
+			wantOK: true,
+		},
+		{
+			name: "all succeed",
+			handlers: map[string]func(context.Context, StockLow) error{
+				"a": func(context.Context, StockLow) error { return nil },
+				"b": func(context.Context, StockLow) error { return nil },
+			},
+			wantOK: true,
+		},
+		{
+			name: "one fails",
+			handlers: map[string]func(context.Context, StockLow) error{
+				"ok":    func(context.Context, StockLow) error { return nil },
+				"stock": func(context.Context, StockLow) error { return errStock },
+			},
+			wantIs: []error{errStock},
+			wantIn: []string{"stock:"},
+		},
+		{
+			name: "every failure is kept",
+			handlers: map[string]func(context.Context, StockLow) error{
+				"stock": func(context.Context, StockLow) error { return errStock },
+				"mail":  func(context.Context, StockLow) error { return errMail },
+				"ok":    func(context.Context, StockLow) error { return nil },
+			},
+			wantIs: []error{errStock, errMail},
+			wantIn: []string{"stock:", "mail:"},
+		},
+		{
+			name: "a panic is an error",
+			handlers: map[string]func(context.Context, StockLow) error{
+				"boom": func(context.Context, StockLow) error { panic("nil map") },
+				"mail": func(context.Context, StockLow) error { return errMail },
+			},
+			wantIs: []error{errMail},
+			wantIn: []string{"boom: handler panicked: nil map", "mail:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewBus(nil, nil)
+			var calls atomic.Int32
+			for name, handle := range tt.handlers {
+				Subscribe(bus, name, 1, func(ctx context.Context, ev StockLow) error {
+					calls.Add(1)
+					return handle(ctx, ev)
+				})
+			}
+
+			err := bus.Dispatch(context.Background(), mustEncode(t, StockLow{ProductID: 1}))
+			if got := int(calls.Load()); got != len(tt.handlers) {
+				t.Errorf("%d subscribers ran, want all %d", got, len(tt.handlers))
+			}
+			if tt.wantOK {
+				if err != nil {
+					t.Fatalf("dispatch: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("dispatch succeeded, want the failures")
+			}
+			for _, want := range tt.wantIs {
+				if !errors.Is(err, want) {
+					t.Errorf("error %q does not wrap %q", err, want)
+				}
+			}
+			for _, want := range tt.wantIn {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchOnlyRunsSubscribersOfTheType(t *testing.T) {
+	bus := NewBus(nil, nil)
+	var stock, users atomic.Int32
+	Subscribe(bus, "stock", 1, func(context.Context, StockLow) error { stock.Add(1); return nil })
+	Subscribe(bus, "users", 1, func(context.Context, UserRegistered) error { users.Add(1); return nil })
+
+	if err := bus.Dispatch(context.Background(), mustEncode(t, UserRegistered{UserID: 4})); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	if stock.Load() != 0 || users.Load() != 1 {
+		t.Errorf("stock ran %d times and users %d, want 0 and 1", stock.Load(), users.Load())
+	}
+}
+
+func TestDispatchPassesEventID(t *testing.T) {
+	bus := NewBus(nil, nil)
+	var got []string
+	Subscribe(bus, "ids", 1, func(ctx context.Context, _ StockLow) error {
+		got = append(got, EventID(ctx))
+		return nil
+	})
+
+	raw := mustEncode(t, StockLow{ProductID: 1})
+	for range 2 {
+		if err := bus.Dispatch(context.Background(), raw); err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+	}
+	if len(got) != 2 || got[0] != raw.ID || got[1] != raw.ID {
+		t.Errorf("handler saw event IDs %q, want %q twice", got, raw.ID)
+	}
+	if id := EventID(context.Background()); id != "" {
+		t.Errorf("EventID outside a handler = %q, want empty", id)
+	}
+}
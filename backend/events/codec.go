@@ -0,0 +1,162 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"swiggy-clone/backend/kafka"
+	"swiggy-clone/backend/models"
+)
+
+// codec knows the payload version this build writes for an event type and
+// how to read it back. Readers accept that version and every older one, so
+// a new payload version can roll out while older consumers still run.
+type codec struct {
+	version int
+	decode  map[int]decoder // by payload version
+}
+
+type decoder func(ctx context.Context, raw json.RawMessage, orders OrderLoader) (Event, error)
+
+var codecs = map[string]*codec{}
+
+// renamed maps event types written by older builds to their current name
+var renamed = map[string]string{
+	"payment.succeeded": TypePaymentCaptured,
+}
+
+// OrderLoader looks up an order for decoders of older payload versions,
+// which carried no more than the order ID
+type OrderLoader func(ctx context.Context, orderID uint) (OrderFacts, error)
+
+// OrderFacts is what the order row says about an order
+type OrderFacts struct {
+	UserID uint
+	Admins []string
+	Total  models.Money
+	Method string // payment method, empty while unpaid
+}
+
+func register[E Event](version int) {
+	var zero E
+	codecs[zero.Type()] = &codec{
+		version: version,
+		decode: map[int]decoder{
+			version: func(_ context.Context, raw json.RawMessage, _ OrderLoader) (Event, error) {
+				var ev E
+				err := json.Unmarshal(raw, &ev)
+				return ev, err
+			},
+		},
+	}
+}
+
+// upgrade registers how to read an older payload version of typ
+func upgrade(typ string, version int, decode decoder) {
+	codecs[typ].decode[version] = decode
+}
+
+func init() {
+	register[OrderPlaced](2)
+	register[OrderStatusChanged](1)
+	register[PaymentCaptured](2)
+	register[StockLow](1)
+	register[UserRegistered](1)
+
+	// Version 1 of the order events started out as just {"orderId"}
+	upgrade(TypeOrderPlaced, 1, decodeOrderPlacedV1)
+	upgrade(TypePaymentCaptured, 1, decodePaymentCapturedV1)
+}
+
+func decodeOrderPlacedV1(ctx context.Context, raw json.RawMessage, orders OrderLoader) (Event, error) {
+	var ev OrderPlaced
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		return nil, err
+	}
+	facts, err := loadOrder(ctx, orders, ev.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	ev.UserID, ev.Admins, ev.Total = facts.UserID, facts.Admins, facts.Total
+	return ev, nil
+}
+
+func decodePaymentCapturedV1(ctx context.Context, raw json.RawMessage, orders OrderLoader) (Event, error) {
+	var ev PaymentCaptured
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		return nil, err
+	}
+	facts, err := loadOrder(ctx, orders, ev.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	ev.UserID, ev.Admins, ev.Amount, ev.Method = facts.UserID, facts.Admins, facts.Total, facts.Method
+	return ev, nil
+}
+
+func loadOrder(ctx context.Context, orders OrderLoader, orderID uint) (OrderFacts, error) {
+	if orderID == 0 {
+		return OrderFacts{}, fmt.Errorf("%w: event without an order ID", kafka.ErrUnprocessable)
+	}
+	if orders == nil {
+		return OrderFacts{}, fmt.Errorf("%w: no order loader for an old event version", kafka.ErrUnprocessable)
+	}
+	return orders(ctx, orderID)
+}
+
+// Encode wraps a typed event for the transports, with a fresh event ID
+func Encode(ev Event) (kafka.Event, error) {
+	c, ok := codecs[ev.Type()]
+	if !ok {
+		return kafka.Event{}, fmt.Errorf("unregistered event type %q", ev.Type())
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return kafka.Event{}, fmt.Errorf("encode %s: %w", ev.Type(), err)
+	}
+	out := kafka.Event{
+		ID:         uuid.NewString(),
+		Type:       ev.Type(),
+		Version:    c.version,
+		Key:        ev.Key(),
+		OccurredAt: time.Now().UTC(),
+		Payload:    payload,
+	}
+	if o, ok := ev.(orderScoped); ok {
+		out.OrderID = o.orderID()
+	}
+	return out, nil
+}
+
+// Decode turns a transported event back into its typed form. Types and
+// versions this build doesn't know are unprocessable, not retryable. Older
+// versions of the order events are completed from the order row by orders.
+func Decode(ctx context.Context, raw kafka.Event, orders OrderLoader) (Event, error) {
+	typ := raw.Type
+	if current, ok := renamed[typ]; ok {
+		typ = current
+	}
+	c, ok := codecs[typ]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown event type %q", kafka.ErrUnprocessable, raw.Type)
+	}
+	decode, ok := c.decode[raw.Version]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported %s version %d", kafka.ErrUnprocessable, raw.Type, raw.Version)
+	}
+	ev, err := decode(ctx, raw.Payload, orders)
+	var syntax *json.SyntaxError
+	var mismatch *json.UnmarshalTypeError
+	if errors.As(err, &syntax) || errors.As(err, &mismatch) {
+		return nil, fmt.Errorf("%w: decode %s: %v", kafka.ErrUnprocessable, raw.Type, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", raw.Type, err)
+	}
+	return ev, nil
+}
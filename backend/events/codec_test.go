@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"swiggy-clone/backend/kafka"
+	"swiggy-clone/backend/models"
+)
+
+// fakeOrders answers OrderLoader lookups from a fixed set of orders
+type fakeOrders map[uint]OrderFacts
+
+func (f fakeOrders) load(_ context.Context, id uint) (OrderFacts, error) {
+	facts, ok := f[id]
+	if !ok {
+		return OrderFacts{}, errors.New("order not found")
+	}
+	return facts, nil
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	actor := uint(3)
+	tests := []Event{
+		OrderPlaced{OrderID: 1, UserID: 2, Admins: []string{"5"}, Total: models.NewMoney(45000)},
+		OrderStatusChanged{OrderID: 1, UserID: 2, Admins: []string{"5"}, From: models.OrderPlaced,
+			To: models.OrderAccepted, Actor: models.ActorAdmin, ActorID: &actor, Reason: "ok"},
+		PaymentCaptured{OrderID: 1, UserID: 2, Admins: []string{"5"}, Amount: models.NewMoney(45000), Method: "UPI"},
+		StockLow{ProductID: 9, AdminID: 5, Name: "Dosa", Stock: 2, Threshold: 5},
+		UserRegistered{UserID: 2, Email: "a@b.c", Name: "A", Role: "USER"},
+	}
+	for _, want := range tests {
+		t.Run(want.Type(), func(t *testing.T) {
+			raw := mustEncode(t, want)
+			if raw.Version != codecs[want.Type()].version {
+				t.Errorf("encoded version %d, want %d", raw.Version, codecs[want.Type()].version)
+			}
+			if raw.Key != want.Key() {
+				t.Errorf("key %q, want %q", raw.Key, want.Key())
+			}
+			got, err := Decode(context.Background(), raw, nil)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeVersionCheck(t *testing.T) {
+	orders := fakeOrders{
+		7: {UserID: 2, Admins: []string{"5", "6"}, Total: models.NewMoney(45000), Method: "CARD"},
+	}
+	tests := []struct {
+		name    string
+		raw     kafka.Event
+		orders  OrderLoader
+		want    Event
+		wantErr error // nil for success; kafka.ErrUnprocessable for events never to retry
+	}{
+		{
+			name: "current version",
+			raw: kafka.Event{Type: TypeOrderPlaced, Version: 2,
+				Payload: json.RawMessage(`{"orderId":7,"userId":2,"admins":["5"],"total":450.00}`)},
+			want: OrderPlaced{OrderID: 7, UserID: 2, Admins: []string{"5"}, Total: models.NewMoney(45000)},
+		},
+		{
+			name:   "v1 order.placed is filled in from the order",
+			raw:    kafka.Event{Type: TypeOrderPlaced, Version: 1, Payload: json.RawMessage(`{"orderId":7}`)},
+			orders: orders.load,
+			want:   OrderPlaced{OrderID: 7, UserID: 2, Admins: []string{"5", "6"}, Total: models.NewMoney(45000)},
+		},
+		{
+			name:   "v1 payment is filled in from the order",
+			raw:    kafka.Event{Type: TypePaymentCaptured, Version: 1, Payload: json.RawMessage(`{"orderId":7}`)},
+			orders: orders.load,
+			want: PaymentCaptured{OrderID: 7, UserID: 2, Admins: []string{"5", "6"},
+				Amount: models.NewMoney(45000), Method: "CARD"},
+		},
+		{
+			name:   "payment.succeeded reads as payment.captured",
+			raw:    kafka.Event{Type: "payment.succeeded", Version: 1, Payload: json.RawMessage(`{"orderId":7}`)},
+			orders: orders.load,
+			want: PaymentCaptured{OrderID: 7, UserID: 2, Admins: []string{"5", "6"},
+				Amount: models.NewMoney(45000), Method: "CARD"},
+		},
+		{
+			name:    "newer version than this build",
+			raw:     kafka.Event{Type: TypeOrderPlaced, Version: 3, Payload: json.RawMessage(`{"orderId":7}`)},
+			orders:  orders.load,
+			wantErr: kafka.ErrUnprocessable,
+		},
+		{
+			name:    "version zero",
+			raw:     kafka.Event{Type: TypeStockLow, Version: 0, Payload: json.RawMessage(`{"productId":1}`)},
+			wantErr: kafka.ErrUnprocessable,
+		},
+		{
+			name:    "older version without an upgrade",
+			raw:     kafka.Event{Type: TypeOrderPlaced, Version: 0, Payload: json.RawMessage(`{"orderId":7}`)},
+			orders:  orders.load,
+			wantErr: kafka.ErrUnprocessable,
+		},
+		{
+			name:    "unknown type",
+			raw:     kafka.Event{Type: "order.teleported", Version: 1, Payload: json.RawMessage(`{}`)},
+			wantErr: kafka.ErrUnprocessable,
+		},
+		{
+			name:    "malformed payload",
+			raw:     kafka.Event{Type: TypeStockLow, Version: 1, Payload: json.RawMessage(`{"productId":`)},
+			wantErr: kafka.ErrUnprocessable,
+		},
+		{
+			name:    "payload of the wrong shape",
+			raw:     kafka.Event{Type: TypeStockLow, Version: 1, Payload: json.RawMessage(`{"productId":"nine"}`)},
+			wantErr: kafka.ErrUnprocessable,
+		},
+		{
+			name:    "v1 without an order ID",
+			raw:     kafka.Event{Type: TypeOrderPlaced, Version: 1, Payload: json.RawMessage(`{}`)},
+			orders:  orders.load,
+			wantErr: kafka.ErrUnprocessable,
+		},
+		{
+			name:    "v1 without an order loader",
+			raw:     kafka.Event{Type: TypeOrderPlaced, Version: 1, Payload: json.RawMessage(`{"orderId":7}`)},
+			wantErr: kafka.ErrUnprocessable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(context.Background(), tt.raw, tt.orders)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("decode = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeOrderLookupFailureIsRetryable(t *testing.T) {
+	raw := kafka.Event{Type: TypeOrderPlaced, Version: 1, Payload: json.RawMessage(`{"orderId":8}`)}
+	_, err := Decode(context.Background(), raw, fakeOrders{}.load)
+	if err == nil {
+		t.Fatal("decode succeeded without the order")
+	}
+	if errors.Is(err, kafka.ErrUnprocessable) {
+		t.Errorf("a failed order lookup should be retried, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package events is the in-process face of the domain event bus: typed
+// events, their wire versions, and the subscribers that react to them.
+package events
+
+import (
+	"fmt"
+
+	"swiggy-clone/backend/models"
+)
+
+// Event is a domain event. Type names it on the wire, and events with the
+// same Key are delivered in the order they were published.
+type Event interface {
+	Type() string
+	Key() string
+}
+
+// orderScoped events are about one order; the queues keep its ID so failed
+// deliveries can be shown to that order's admins
+type orderScoped interface {
+	orderID() uint
+}
+
+const (
+	TypeOrderPlaced        = "order.placed"
+	TypeOrderStatusChanged = "order.status_changed"
+	TypePaymentCaptured    = "payment.captured"
+	TypeStockLow           = "stock.low"
+	TypeUserRegistered     = "user.registered"
+)
+
+// OrderPlaced is published when checkout creates an order
+type OrderPlaced struct {
+	OrderID uint         `json:"orderId"`
+	UserID  uint         `json:"userId"`
+	Admins  []string     `json:"admins"` // restaurant admins with products in the order
+	Total   models.Money `json:"total"`
+}
+
+// OrderStatusChanged is published for every step of the order lifecycle
+type OrderStatusChanged struct {
+	OrderID uint               `json:"orderId"`
+	UserID  uint               `json:"userId"`
+	Admins  []string           `json:"admins"`
+	From    models.OrderStatus `json:"from"`
+	To      models.OrderStatus `json:"to"`
+	Actor   models.OrderActor  `json:"actor"`
+	ActorID *uint              `json:"actorId,omitempty"`
+	Reason  string             `json:"reason"`
+}
+
+// PaymentCaptured is published once an order's payments are recorded
+type PaymentCaptured struct {
+	OrderID uint         `json:"orderId"`
+	UserID  uint         `json:"userId"`
+	Admins  []string     `json:"admins"`
+	Amount  models.Money `json:"amount"`
+	Method  string       `json:"method"`
+}
+
+// StockLow is published when a product's stock falls to its low-stock
+// threshold, or runs out (Stock 0)
+type StockLow struct {
+	ProductID uint   `json:"productId"`
+	AdminID   uint   `json:"adminId"`
+	Name      string `json:"name"`
+	Stock     int    `json:"stock"`
+	Threshold int    `json:"threshold"`
+}
+
+// UserRegistered is published when someone signs up
+type UserRegistered struct {
+	UserID uint   `json:"userId"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+}
+
+func (OrderPlaced) Type() string        { return TypeOrderPlaced }
+func (OrderStatusChanged) Type() string { return TypeOrderStatusChanged }
+func (PaymentCaptured) Type() string    { return TypePaymentCaptured }
+func (StockLow) Type() string           { return TypeStockLow }
+func (UserRegistered) Type() string     { return TypeUserRegistered }
+
+func (e OrderPlaced) Key() string        { return orderKey(e.OrderID) }
+func (e OrderStatusChanged) Key() string { return orderKey(e.OrderID) }
+func (e PaymentCaptured) Key() string    { return orderKey(e.OrderID) }
+func (e StockLow) Key() string           { return fmt.Sprintf("product:%d", e.ProductID) }
+func (e UserRegistered) Key() string     { return fmt.Sprintf("user:%d", e.UserID) }
+
+func (e OrderPlaced) orderID() uint        { return e.OrderID }
+func (e OrderStatusChanged) orderID() uint { return e.OrderID }
+func (e PaymentCaptured) orderID() uint    { return e.OrderID }
+
+func orderKey(id uint) string { return fmt.Sprintf("order:%d", id) }
@@ -7,80 +7,300 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"swiggy-clone/backend/models"
 	"time"
 )
 
 type AuthPayload struct {
-	Token string `json:"token"`
-	User  *User  `json:"user"`
-	Role  string `json:"role"`
+	Token        string `json:"token"`
+	User         *User  `json:"user"`
+	Role         string `json:"role"`
+	CartConflict bool   `json:"cartConflict"`
+}
+
+type BillItem struct {
+	ProductID    string       `json:"productId"`
+	Name         string       `json:"name"`
+	Category     string       `json:"category"`
+	RestaurantID string       `json:"restaurantId"`
+	Quantity     int          `json:"quantity"`
+	UnitPrice    models.Money `json:"unitPrice"`
+	LineTotal    models.Money `json:"lineTotal"`
+	GstRate      float64      `json:"gstRate"`
+	Gst          models.Money `json:"gst"`
 }
 
 type Cart struct {
-	Items []*CartItem `json:"items"`
-	Total float64     `json:"total"`
+	Items        []*CartItem    `json:"items"`
+	Total        models.Money   `json:"total"`
+	Bill         *CartBill      `json:"bill"`
+	Coupon       *string        `json:"coupon,omitempty"`
+	CouponError  *string        `json:"couponError,omitempty"`
+	Warnings     []*CartWarning `json:"warnings"`
+	CanCheckout  bool           `json:"canCheckout"`
+	RestaurantID *string        `json:"restaurantId,omitempty"`
+}
+
+type CartBill struct {
+	Items         []*BillItem       `json:"items"`
+	Restaurants   []*RestaurantBill `json:"restaurants"`
+	Subtotal      models.Money      `json:"subtotal"`
+	Gst           models.Money      `json:"gst"`
+	Packaging     models.Money      `json:"packaging"`
+	Delivery      models.Money      `json:"delivery"`
+	SmallOrderFee models.Money      `json:"smallOrderFee"`
+	PlatformFee   models.Money      `json:"platformFee"`
+	Total         models.Money      `json:"total"`
+	Discount      models.Money      `json:"discount"`
+	CouponCode    *string           `json:"couponCode,omitempty"`
 }
 
 type CartItem struct {
-	Product  *Product `json:"product"`
-	Quantity int      `json:"quantity"`
+	Product      *Product       `json:"product"`
+	Quantity     int            `json:"quantity"`
+	AddedPrice   *models.Money  `json:"addedPrice,omitempty"`
+	PriceChanged bool           `json:"priceChanged"`
+	Warnings     []*CartWarning `json:"warnings"`
+}
+
+type CartWarning struct {
+	ProductID string          `json:"productId"`
+	Code      CartWarningCode `json:"code"`
+	Message   string          `json:"message"`
+}
+
+type Coupon struct {
+	ID             string       `json:"id"`
+	Code           string       `json:"code"`
+	Description    string       `json:"description"`
+	Type           CouponType   `json:"type"`
+	PercentOff     float64      `json:"percentOff"`
+	FlatAmount     models.Money `json:"flatAmount"`
+	MinOrder       models.Money `json:"minOrder"`
+	MaxDiscount    models.Money `json:"maxDiscount"`
+	UsageLimit     int          `json:"usageLimit"`
+	PerUserLimit   int          `json:"perUserLimit"`
+	UsedCount      int          `json:"usedCount"`
+	FirstOrderOnly bool         `json:"firstOrderOnly"`
+	RestaurantID   *string      `json:"restaurantId,omitempty"`
+	ValidFrom      *time.Time   `json:"validFrom,omitempty"`
+	ValidUntil     *time.Time   `json:"validUntil,omitempty"`
+	Active         bool         `json:"active"`
+}
+
+type CouponInput struct {
+	Code           string        `json:"code"`
+	Description    *string       `json:"description,omitempty"`
+	Type           CouponType    `json:"type"`
+	PercentOff     *float64      `json:"percentOff,omitempty"`
+	FlatAmount     *models.Money `json:"flatAmount,omitempty"`
+	MinOrder       *models.Money `json:"minOrder,omitempty"`
+	MaxDiscount    *models.Money `json:"maxDiscount,omitempty"`
+	UsageLimit     *int          `json:"usageLimit,omitempty"`
+	PerUserLimit   *int          `json:"perUserLimit,omitempty"`
+	FirstOrderOnly *bool         `json:"firstOrderOnly,omitempty"`
+	ValidFrom      *time.Time    `json:"validFrom,omitempty"`
+	ValidUntil     *time.Time    `json:"validUntil,omitempty"`
+}
+
+type FailedJob struct {
+	ID         string     `json:"id"`
+	OrderID    string     `json:"orderId"`
+	EventType  string     `json:"eventType"`
+	Queue      string     `json:"queue"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"lastError"`
+	EnqueuedAt time.Time  `json:"enqueuedAt"`
+	FailedAt   time.Time  `json:"failedAt"`
+	ReplayedAt *time.Time `json:"replayedAt,omitempty"`
+}
+
+type Favorite struct {
+	ID           string         `json:"id"`
+	Kind         FavoriteKind   `json:"kind"`
+	TargetID     string         `json:"targetId"`
+	Product      *Product       `json:"product,omitempty"`
+	Restaurant   *Restaurant    `json:"restaurant,omitempty"`
+	CurrentPrice *models.Money  `json:"currentPrice,omitempty"`
+	Available    bool           `json:"available"`
+	Warnings     []*CartWarning `json:"warnings"`
+	CreatedAt    time.Time      `json:"createdAt"`
+}
+
+type GstRate struct {
+	Category string  `json:"category"`
+	Rate     float64 `json:"rate"`
+}
+
+type GstRateInput struct {
+	Category string  `json:"category"`
+	Rate     float64 `json:"rate"`
 }
 
 type Mutation struct {
 }
 
+type Notification struct {
+	ID        string           `json:"id"`
+	Kind      NotificationKind `json:"kind"`
+	Message   string           `json:"message"`
+	ProductID *string          `json:"productId,omitempty"`
+	Read      bool             `json:"read"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
 type Order struct {
-	ID             string         `json:"id"`
-	UserID         string         `json:"user_id"`
-	Products       []*ProductItem `json:"products"`
-	ProductAdmins  []string       `json:"product_admins"`
-	TotalPrice     float64        `json:"total_price"`
-	Status         OrderStatus    `json:"status"`
-	PlacedAt       time.Time      `json:"placedAt"`
-	Items          []*OrderItem   `json:"items"`
-	IdempotencyKey *string        `json:"idempotencyKey,omitempty"`
+	ID               string               `json:"id"`
+	UserID           string               `json:"user_id"`
+	Products         []*ProductItem       `json:"products"`
+	ProductAdmins    []string             `json:"product_admins"`
+	TotalPrice       models.Money         `json:"total_price"`
+	Status           OrderStatus          `json:"status"`
+	PlacedAt         time.Time            `json:"placedAt"`
+	Items            []*OrderItem         `json:"items"`
+	IdempotencyKey   *string              `json:"idempotencyKey,omitempty"`
+	Bill             *CartBill            `json:"bill,omitempty"`
+	StatusHistory    []*OrderStatusChange `json:"statusHistory"`
+	PrepMinutes      *int                 `json:"prepMinutes,omitempty"`
+	EstimatedReadyAt *time.Time           `json:"estimatedReadyAt,omitempty"`
+	CancelReason     *string              `json:"cancelReason,omitempty"`
+	RefundAmount     models.Money         `json:"refundAmount"`
 }
 
 type OrderItem struct {
-	ProductID       string   `json:"productId"`
-	Quantity        int      `json:"quantity"`
-	PriceAtPurchase float64  `json:"priceAtPurchase"`
-	Product         *Product `json:"product,omitempty"`
+	ProductID       string       `json:"productId"`
+	Quantity        int          `json:"quantity"`
+	PriceAtPurchase models.Money `json:"priceAtPurchase"`
+	Product         *Product     `json:"product,omitempty"`
+}
+
+type OrderStatusChange struct {
+	From    *OrderStatus `json:"from,omitempty"`
+	To      OrderStatus  `json:"to"`
+	Actor   OrderActor   `json:"actor"`
+	ActorID *string      `json:"actorId,omitempty"`
+	Reason  string       `json:"reason"`
+	At      time.Time    `json:"at"`
 }
 
 type Payment struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"userId"`
-	AdminID   string    `json:"adminId"`
-	OrderID   string    `json:"orderID"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"`
-	Method    string    `json:"method"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID             string       `json:"id"`
+	UserID         string       `json:"userId"`
+	AdminID        string       `json:"adminId"`
+	OrderID        string       `json:"orderID"`
+	Amount         models.Money `json:"amount"`
+	Status         string       `json:"status"`
+	Method         string       `json:"method"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	RefundedAmount models.Money `json:"refundedAmount"`
+}
+
+type PriceChange struct {
+	ID          string            `json:"id"`
+	ProductID   string            `json:"productId"`
+	Price       models.Money      `json:"price"`
+	EffectiveAt time.Time         `json:"effectiveAt"`
+	Status      PriceChangeStatus `json:"status"`
+	CreatedAt   time.Time         `json:"createdAt"`
 }
 
 type Product struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	Stock     int       `json:"stock"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	AdminID   int       `json:"adminId"`
-	Image     *string   `json:"image,omitempty"`
-	Quantity  *string   `json:"quantity,omitempty"`
+	ID                string         `json:"id"`
+	Name              string         `json:"name"`
+	Price             models.Money   `json:"price"`
+	Stock             int            `json:"stock"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	AdminID           int            `json:"adminId"`
+	Image             *string        `json:"image,omitempty"`
+	Quantity          *string        `json:"quantity,omitempty"`
+	ImageURL          *string        `json:"imageUrl,omitempty"`
+	ThumbnailURL      *string        `json:"thumbnailUrl,omitempty"`
+	LowStockThreshold int            `json:"lowStockThreshold"`
+	Available         bool           `json:"available"`
+	PriceHistory      []*PriceChange `json:"priceHistory"`
+	Category          string         `json:"category"`
 }
 
 type ProductItem struct {
-	ProductID       string   `json:"productId"`
-	Quantity        int      `json:"quantity"`
-	PriceAtPurchase float64  `json:"priceAtPurchase"`
-	Product         *Product `json:"product,omitempty"`
+	ProductID       string       `json:"productId"`
+	Quantity        int          `json:"quantity"`
+	PriceAtPurchase models.Money `json:"priceAtPurchase"`
+	Product         *Product     `json:"product,omitempty"`
 }
 
 type Query struct {
 }
 
+type ReorderResult struct {
+	Cart    *Cart                 `json:"cart"`
+	Skipped []*ReorderSkippedItem `json:"skipped"`
+}
+
+type ReorderSkippedItem struct {
+	ProductID string            `json:"productId"`
+	Name      string            `json:"name"`
+	Quantity  int               `json:"quantity"`
+	Reason    ReorderSkipReason `json:"reason"`
+	Message   string            `json:"message"`
+}
+
+type Restaurant struct {
+	ID                  string       `json:"id"`
+	AdminID             string       `json:"adminId"`
+	Name                string       `json:"name"`
+	Lat                 *float64     `json:"lat,omitempty"`
+	Lng                 *float64     `json:"lng,omitempty"`
+	GstRates            []*GstRate   `json:"gstRates"`
+	DefaultGstRate      float64      `json:"defaultGstRate"`
+	PackagingFee        models.Money `json:"packagingFee"`
+	DeliveryBaseFee     models.Money `json:"deliveryBaseFee"`
+	DeliveryBaseKm      float64      `json:"deliveryBaseKm"`
+	DeliveryPerKm       models.Money `json:"deliveryPerKm"`
+	SmallOrderThreshold models.Money `json:"smallOrderThreshold"`
+	SmallOrderFee       models.Money `json:"smallOrderFee"`
+	PlatformFee         models.Money `json:"platformFee"`
+	Open                bool         `json:"open"`
+}
+
+type RestaurantBill struct {
+	RestaurantID  string       `json:"restaurantId"`
+	Subtotal      models.Money `json:"subtotal"`
+	Gst           models.Money `json:"gst"`
+	Packaging     models.Money `json:"packaging"`
+	Delivery      models.Money `json:"delivery"`
+	DistanceKm    *float64     `json:"distanceKm,omitempty"`
+	SmallOrderFee models.Money `json:"smallOrderFee"`
+}
+
+type RestaurantInput struct {
+	Name                string          `json:"name"`
+	Lat                 *float64        `json:"lat,omitempty"`
+	Lng                 *float64        `json:"lng,omitempty"`
+	GstRates            []*GstRateInput `json:"gstRates,omitempty"`
+	DefaultGstRate      float64         `json:"defaultGstRate"`
+	PackagingFee        models.Money    `json:"packagingFee"`
+	DeliveryBaseFee     models.Money    `json:"deliveryBaseFee"`
+	DeliveryBaseKm      float64         `json:"deliveryBaseKm"`
+	DeliveryPerKm       models.Money    `json:"deliveryPerKm"`
+	SmallOrderThreshold models.Money    `json:"smallOrderThreshold"`
+	SmallOrderFee       models.Money    `json:"smallOrderFee"`
+	PlatformFee         models.Money    `json:"platformFee"`
+}
+
+type SavedItem struct {
+	ID           string         `json:"id"`
+	ProductID    string         `json:"productId"`
+	Product      *Product       `json:"product,omitempty"`
+	Quantity     int            `json:"quantity"`
+	SavedPrice   models.Money   `json:"savedPrice"`
+	CurrentPrice *models.Money  `json:"currentPrice,omitempty"`
+	PriceChanged bool           `json:"priceChanged"`
+	Available    bool           `json:"available"`
+	Warnings     []*CartWarning `json:"warnings"`
+	SavedAt      time.Time      `json:"savedAt"`
+}
+
 type SignupInput struct {
 	Email    string  `json:"email"`
 	Password string  `json:"password"`
@@ -89,34 +309,359 @@ type SignupInput struct {
 	Picture  *string `json:"picture,omitempty"`
 }
 
+type StockMovement struct {
+	ID         string              `json:"id"`
+	ProductID  string              `json:"productId"`
+	Delta      int                 `json:"delta"`
+	StockAfter int                 `json:"stockAfter"`
+	Reason     StockMovementReason `json:"reason"`
+	OrderID    *string             `json:"orderId,omitempty"`
+	ActorID    *string             `json:"actorId,omitempty"`
+	Note       string              `json:"note"`
+	CreatedAt  time.Time           `json:"createdAt"`
+}
+
+type StockReservation struct {
+	ID        string            `json:"id"`
+	OrderID   string            `json:"orderId"`
+	ProductID string            `json:"productId"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+type Subscription struct {
+}
+
 type User struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Role      string    `json:"role"`
-	Picture   *string   `json:"picture,omitempty"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID            string       `json:"id"`
+	Email         string       `json:"email"`
+	Name          string       `json:"name"`
+	Role          string       `json:"role"`
+	Picture       *string      `json:"picture,omitempty"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	SavedForLater []*SavedItem `json:"savedForLater"`
+	Favorites     []*Favorite  `json:"favorites"`
+}
+
+type CartWarningCode string
+
+const (
+	CartWarningCodePriceChanged      CartWarningCode = "PRICE_CHANGED"
+	CartWarningCodeOutOfStock        CartWarningCode = "OUT_OF_STOCK"
+	CartWarningCodeInsufficientStock CartWarningCode = "INSUFFICIENT_STOCK"
+	CartWarningCodeUnavailable       CartWarningCode = "UNAVAILABLE"
+	CartWarningCodeRestaurantClosed  CartWarningCode = "RESTAURANT_CLOSED"
+	CartWarningCodeRemoved           CartWarningCode = "REMOVED"
+)
+
+var AllCartWarningCode = []CartWarningCode{
+	CartWarningCodePriceChanged,
+	CartWarningCodeOutOfStock,
+	CartWarningCodeInsufficientStock,
+	CartWarningCodeUnavailable,
+	CartWarningCodeRestaurantClosed,
+	CartWarningCodeRemoved,
+}
+
+func (e CartWarningCode) IsValid() bool {
+	switch e {
+	case CartWarningCodePriceChanged, CartWarningCodeOutOfStock, CartWarningCodeInsufficientStock, CartWarningCodeUnavailable, CartWarningCodeRestaurantClosed, CartWarningCodeRemoved:
+		return true
+	}
+	return false
+}
+
+func (e CartWarningCode) String() string {
+	return string(e)
+}
+
+func (e *CartWarningCode) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CartWarningCode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CartWarningCode", str)
+	}
+	return nil
+}
+
+func (e CartWarningCode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *CartWarningCode) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e CartWarningCode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type CouponType string
+
+const (
+	CouponTypePercentage   CouponType = "PERCENTAGE"
+	CouponTypeFlat         CouponType = "FLAT"
+	CouponTypeFreeDelivery CouponType = "FREE_DELIVERY"
+)
+
+var AllCouponType = []CouponType{
+	CouponTypePercentage,
+	CouponTypeFlat,
+	CouponTypeFreeDelivery,
+}
+
+func (e CouponType) IsValid() bool {
+	switch e {
+	case CouponTypePercentage, CouponTypeFlat, CouponTypeFreeDelivery:
+		return true
+	}
+	return false
+}
+
+func (e CouponType) String() string {
+	return string(e)
+}
+
+func (e *CouponType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CouponType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CouponType", str)
+	}
+	return nil
+}
+
+func (e CouponType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *CouponType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e CouponType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type FavoriteKind string
+
+const (
+	FavoriteKindProduct    FavoriteKind = "PRODUCT"
+	FavoriteKindRestaurant FavoriteKind = "RESTAURANT"
+)
+
+var AllFavoriteKind = []FavoriteKind{
+	FavoriteKindProduct,
+	FavoriteKindRestaurant,
+}
+
+func (e FavoriteKind) IsValid() bool {
+	switch e {
+	case FavoriteKindProduct, FavoriteKindRestaurant:
+		return true
+	}
+	return false
+}
+
+func (e FavoriteKind) String() string {
+	return string(e)
+}
+
+func (e *FavoriteKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FavoriteKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FavoriteKind", str)
+	}
+	return nil
+}
+
+func (e FavoriteKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *FavoriteKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e FavoriteKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type NotificationKind string
+
+const (
+	NotificationKindLowStock   NotificationKind = "LOW_STOCK"
+	NotificationKindOutOfStock NotificationKind = "OUT_OF_STOCK"
+	NotificationKindNewOrder   NotificationKind = "NEW_ORDER"
+	NotificationKindOrderPaid  NotificationKind = "ORDER_PAID"
+)
+
+var AllNotificationKind = []NotificationKind{
+	NotificationKindLowStock,
+	NotificationKindOutOfStock,
+	NotificationKindNewOrder,
+	NotificationKindOrderPaid,
+}
+
+func (e NotificationKind) IsValid() bool {
+	switch e {
+	case NotificationKindLowStock, NotificationKindOutOfStock, NotificationKindNewOrder, NotificationKindOrderPaid:
+		return true
+	}
+	return false
+}
+
+func (e NotificationKind) String() string {
+	return string(e)
+}
+
+func (e *NotificationKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NotificationKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NotificationKind", str)
+	}
+	return nil
+}
+
+func (e NotificationKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *NotificationKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e NotificationKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type OrderActor string
+
+const (
+	OrderActorUser   OrderActor = "USER"
+	OrderActorAdmin  OrderActor = "ADMIN"
+	OrderActorSystem OrderActor = "SYSTEM"
+)
+
+var AllOrderActor = []OrderActor{
+	OrderActorUser,
+	OrderActorAdmin,
+	OrderActorSystem,
+}
+
+func (e OrderActor) IsValid() bool {
+	switch e {
+	case OrderActorUser, OrderActorAdmin, OrderActorSystem:
+		return true
+	}
+	return false
+}
+
+func (e OrderActor) String() string {
+	return string(e)
+}
+
+func (e *OrderActor) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderActor(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderActor", str)
+	}
+	return nil
+}
+
+func (e OrderActor) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *OrderActor) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e OrderActor) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
 
 type OrderStatus string
 
 const (
-	OrderStatusPending    OrderStatus = "PENDING"
-	OrderStatusProcessing OrderStatus = "PROCESSING"
-	OrderStatusSuccess    OrderStatus = "SUCCESS"
-	OrderStatusFailed     OrderStatus = "FAILED"
+	OrderStatusPlaced    OrderStatus = "PLACED"
+	OrderStatusAccepted  OrderStatus = "ACCEPTED"
+	OrderStatusPreparing OrderStatus = "PREPARING"
+	OrderStatusReady     OrderStatus = "READY"
+	OrderStatusPickedUp  OrderStatus = "PICKED_UP"
+	OrderStatusDelivered OrderStatus = "DELIVERED"
+	OrderStatusCancelled OrderStatus = "CANCELLED"
+	OrderStatusRejected  OrderStatus = "REJECTED"
 )
 
 var AllOrderStatus = []OrderStatus{
-	OrderStatusPending,
-	OrderStatusProcessing,
-	OrderStatusSuccess,
-	OrderStatusFailed,
+	OrderStatusPlaced,
+	OrderStatusAccepted,
+	OrderStatusPreparing,
+	OrderStatusReady,
+	OrderStatusPickedUp,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+	OrderStatusRejected,
 }
 
 func (e OrderStatus) IsValid() bool {
 	switch e {
-	case OrderStatusPending, OrderStatusProcessing, OrderStatusSuccess, OrderStatusFailed:
+	case OrderStatusPlaced, OrderStatusAccepted, OrderStatusPreparing, OrderStatusReady, OrderStatusPickedUp, OrderStatusDelivered, OrderStatusCancelled, OrderStatusRejected:
 		return true
 	}
 	return false
@@ -156,3 +701,243 @@ func (e OrderStatus) MarshalJSON() ([]byte, error) {
 	e.MarshalGQL(&buf)
 	return buf.Bytes(), nil
 }
+
+type PriceChangeStatus string
+
+const (
+	PriceChangeStatusScheduled PriceChangeStatus = "SCHEDULED"
+	PriceChangeStatusApplied   PriceChangeStatus = "APPLIED"
+	PriceChangeStatusCancelled PriceChangeStatus = "CANCELLED"
+)
+
+var AllPriceChangeStatus = []PriceChangeStatus{
+	PriceChangeStatusScheduled,
+	PriceChangeStatusApplied,
+	PriceChangeStatusCancelled,
+}
+
+func (e PriceChangeStatus) IsValid() bool {
+	switch e {
+	case PriceChangeStatusScheduled, PriceChangeStatusApplied, PriceChangeStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e PriceChangeStatus) String() string {
+	return string(e)
+}
+
+func (e *PriceChangeStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PriceChangeStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PriceChangeStatus", str)
+	}
+	return nil
+}
+
+func (e PriceChangeStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PriceChangeStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PriceChangeStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type ReorderSkipReason string
+
+const (
+	ReorderSkipReasonRemoved           ReorderSkipReason = "REMOVED"
+	ReorderSkipReasonOutOfStock        ReorderSkipReason = "OUT_OF_STOCK"
+	ReorderSkipReasonUnavailable       ReorderSkipReason = "UNAVAILABLE"
+	ReorderSkipReasonRestaurantClosed  ReorderSkipReason = "RESTAURANT_CLOSED"
+	ReorderSkipReasonInsufficientStock ReorderSkipReason = "INSUFFICIENT_STOCK"
+	ReorderSkipReasonQuantityLimit     ReorderSkipReason = "QUANTITY_LIMIT"
+	ReorderSkipReasonOtherRestaurant   ReorderSkipReason = "OTHER_RESTAURANT"
+)
+
+var AllReorderSkipReason = []ReorderSkipReason{
+	ReorderSkipReasonRemoved,
+	ReorderSkipReasonOutOfStock,
+	ReorderSkipReasonUnavailable,
+	ReorderSkipReasonRestaurantClosed,
+	ReorderSkipReasonInsufficientStock,
+	ReorderSkipReasonQuantityLimit,
+	ReorderSkipReasonOtherRestaurant,
+}
+
+func (e ReorderSkipReason) IsValid() bool {
+	switch e {
+	case ReorderSkipReasonRemoved, ReorderSkipReasonOutOfStock, ReorderSkipReasonUnavailable, ReorderSkipReasonRestaurantClosed, ReorderSkipReasonInsufficientStock, ReorderSkipReasonQuantityLimit, ReorderSkipReasonOtherRestaurant:
+		return true
+	}
+	return false
+}
+
+func (e ReorderSkipReason) String() string {
+	return string(e)
+}
+
+func (e *ReorderSkipReason) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReorderSkipReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReorderSkipReason", str)
+	}
+	return nil
+}
+
+func (e ReorderSkipReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ReorderSkipReason) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ReorderSkipReason) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type ReservationStatus string
+
+const (
+	ReservationStatusActive   ReservationStatus = "ACTIVE"
+	ReservationStatusConsumed ReservationStatus = "CONSUMED"
+	ReservationStatusReleased ReservationStatus = "RELEASED"
+	ReservationStatusExpired  ReservationStatus = "EXPIRED"
+)
+
+var AllReservationStatus = []ReservationStatus{
+	ReservationStatusActive,
+	ReservationStatusConsumed,
+	ReservationStatusReleased,
+	ReservationStatusExpired,
+}
+
+func (e ReservationStatus) IsValid() bool {
+	switch e {
+	case ReservationStatusActive, ReservationStatusConsumed, ReservationStatusReleased, ReservationStatusExpired:
+		return true
+	}
+	return false
+}
+
+func (e ReservationStatus) String() string {
+	return string(e)
+}
+
+func (e *ReservationStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReservationStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReservationStatus", str)
+	}
+	return nil
+}
+
+func (e ReservationStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ReservationStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ReservationStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type StockMovementReason string
+
+const (
+	StockMovementReasonOrder        StockMovementReason = "ORDER"
+	StockMovementReasonRestock      StockMovementReason = "RESTOCK"
+	StockMovementReasonAdjustment   StockMovementReason = "ADJUSTMENT"
+	StockMovementReasonCancellation StockMovementReason = "CANCELLATION"
+)
+
+var AllStockMovementReason = []StockMovementReason{
+	StockMovementReasonOrder,
+	StockMovementReasonRestock,
+	StockMovementReasonAdjustment,
+	StockMovementReasonCancellation,
+}
+
+func (e StockMovementReason) IsValid() bool {
+	switch e {
+	case StockMovementReasonOrder, StockMovementReasonRestock, StockMovementReasonAdjustment, StockMovementReasonCancellation:
+		return true
+	}
+	return false
+}
+
+func (e StockMovementReason) String() string {
+	return string(e)
+}
+
+func (e *StockMovementReason) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StockMovementReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StockMovementReason", str)
+	}
+	return nil
+}
+
+func (e StockMovementReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *StockMovementReason) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e StockMovementReason) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
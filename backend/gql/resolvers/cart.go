@@ -2,24 +2,29 @@ package resolvers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
 
 	gql "swiggy-clone/backend/gql"
 	"swiggy-clone/backend/middleware"
 	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/pricing"
 	"swiggy-clone/backend/redis"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
 )
 
 func (r *mutationResolver) AddToCart(ctx context.Context, productId string, quantity int) (*gql.Cart, error) {
-	// ✅ Extract user ID from context
-	uid, ok := middleware.UserIDFromCtx(ctx)
+	// ✅ Work out whose cart this is: the signed-in user or a guest session
+	owner, userID, err := r.cartOwner(ctx)
 	fmt.Println("[AddToCart] Entered resolver. Context:", ctx)
 
-	if !ok {
-		return nil, fmt.Errorf("unauthorized: no user ID in context (addToCart)")
+	if err != nil {
+		return nil, err
 	}
-	userID := uid
 
 	// Parse product ID
 	pid, err := strconv.Atoi(productId)
@@ -28,112 +33,396 @@ func (r *mutationResolver) AddToCart(ctx context.Context, productId string, quan
 	}
 
 	// Get existing cart
-	cart, _ := redis.GetCart(ctx, userID)
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if item already exists
-	found := false
-	for i, item := range cart {
+	// Validate against the quantity the cart will end up holding
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be at least 1")
+	}
+	newQty := quantity
+	for _, item := range cart {
 		if item.ProductID == uint(pid) {
-			cart[i].Quantity += quantity
-			found = true
-			break
+			newQty += item.Quantity
 		}
 	}
-	if !found {
-		cart = append(cart, models.CartItem{
-			ProductID: uint(pid),
-			Quantity:  quantity,
-		})
+	// The price is remembered at add time so later price changes can be flagged
+	product, err := r.validateCartLine(ctx, uint(pid), newQty)
+	if err != nil {
+		return nil, err
 	}
 
-	// Save back to Redis
-	if err := redis.SetCart(ctx, userID, cart); err != nil {
+	// The increment itself is atomic in Redis, so concurrent adds from two
+	// tabs both count; the script re-checks the quantity cap
+	_, err = redis.AddToCart(ctx, owner, models.CartItem{
+		ProductID: uint(pid),
+		AdminID:   product.AdminID,
+		Price:     product.Price,
+	}, quantity, r.MaxCartQuantity)
+	var conflict *redis.RestaurantConflictError
+	if errors.As(err, &conflict) {
+		return nil, r.restaurantConflictError(ctx, conflict)
+	}
+	if errors.Is(err, redis.ErrCartQuantityLimit) {
+		return nil, fmt.Errorf("you can add at most %d of an item", r.MaxCartQuantity)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	return r.buildCart(ctx, cart)
+	cart, err = redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, userID, cart)
 }
 
-func (r *mutationResolver) UpdateCart(ctx context.Context, productId string, quantity int) (*gql.Cart, error) {
-	uid, ok := middleware.UserIDFromCtx(ctx)
-	if !ok {
-		return nil, fmt.Errorf("unauthorized: no user ID in context (updateCart)")
+// ReplaceCart clears the cart and puts in just this product, so the user can
+// switch restaurants in one step
+func (r *mutationResolver) ReplaceCart(ctx context.Context, productId string, quantity int) (*gql.Cart, error) {
+	owner, userID, err := r.cartOwner(ctx)
+	if err != nil {
+		return nil, err
 	}
-	userID := uid
 
 	pid, err := strconv.Atoi(productId)
 	if err != nil {
 		return nil, fmt.Errorf("invalid product ID")
 	}
+	product, err := r.validateCartLine(ctx, uint(pid), quantity)
+	if err != nil {
+		return nil, err
+	}
 
-	cart, _ := redis.GetCart(ctx, userID)
+	cart := []models.CartItem{{
+		ProductID: product.ID,
+		AdminID:   product.AdminID,
+		Quantity:  quantity,
+		Price:     product.Price,
+	}}
+	if err := redis.ReplaceCart(ctx, owner, cart); err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, userID, cart)
+}
 
-	for i, item := range cart {
-		if item.ProductID == uint(pid) {
-			cart[i].Quantity = quantity
-			break
+// restaurantConflictError tells the client which restaurant the cart belongs
+// to, so it can offer to replace the cart
+func (r *Resolver) restaurantConflictError(ctx context.Context, conflict *redis.RestaurantConflictError) error {
+	name := ""
+	if rest, err := r.Pricing.Restaurant(ctx, conflict.CurrentAdminID); err == nil && rest != nil && rest.Name != "" {
+		name = rest.Name
+	} else {
+		var admin models.User
+		if err := r.DB.WithContext(ctx).Select("id", "name").First(&admin, conflict.CurrentAdminID).Error; err == nil {
+			name = admin.Name
 		}
 	}
+	return &gqlerror.Error{
+		Message: conflict.Error(),
+		Extensions: map[string]interface{}{
+			"code": "CART_RESTAURANT_CONFLICT",
+			"currentRestaurant": map[string]interface{}{
+				"id":   fmt.Sprint(conflict.CurrentAdminID),
+				"name": name,
+			},
+		},
+	}
+}
+
+func (r *mutationResolver) UpdateCart(ctx context.Context, productId string, quantity int) (*gql.Cart, error) {
+	owner, userID, err := r.cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pid, err := strconv.Atoi(productId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID")
+	}
+
+	if _, err := r.validateCartLine(ctx, uint(pid), quantity); err != nil {
+		return nil, err
+	}
+	if err := redis.SetCartQuantity(ctx, owner, uint(pid), quantity); err != nil {
+		return nil, err
+	}
 
-	redis.SetCart(ctx, userID, cart)
-	return r.buildCart(ctx, cart)
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, userID, cart)
 }
 
 func (r *mutationResolver) RemoveFromCart(ctx context.Context, productId string) (*gql.Cart, error) {
-	uid, ok := middleware.UserIDFromCtx(ctx)
-	if !ok {
-		return nil, fmt.Errorf("unauthorized: no user ID in context (removeFromCart)")
+	owner, userID, err := r.cartOwner(ctx)
+	if err != nil {
+		return nil, err
 	}
-	userID := uid
 
 	pid, err := strconv.Atoi(productId)
 	if err != nil {
 		return nil, fmt.Errorf("invalid product ID")
 	}
 
-	cart, _ := redis.GetCart(ctx, userID)
+	if err := redis.RemoveFromCart(ctx, owner, uint(pid)); err != nil {
+		return nil, err
+	}
 
-	// Filter out the item
-	newCart := []models.CartItem{}
-	for _, item := range cart {
-		if item.ProductID != uint(pid) {
-			newCart = append(newCart, item)
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, userID, cart)
+}
+
+func (r *queryResolver) MyCart(ctx context.Context) (*gql.Cart, error) {
+	owner, userID, err := r.cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, userID, cart)
+}
+
+// StartGuestSession hands an anonymous shopper a signed session for their cart
+func (r *mutationResolver) StartGuestSession(ctx context.Context) (string, error) {
+	_, token, err := middleware.NewGuestSession()
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// mergeGuestCart folds the request's guest cart, if any, into the user's cart
+// and reports whether the two were from different restaurants. Signing in
+// must not fail because of it, so other errors are only logged.
+func (r *Resolver) mergeGuestCart(ctx context.Context, userID uint) (conflict bool) {
+	gid, ok := middleware.GuestIDFromCtx(ctx)
+	if !ok || r.Carts == nil {
+		return false
+	}
+	err := r.Carts.MergeGuestCart(ctx, gid, userID)
+	var restaurantConflict *redis.RestaurantConflictError
+	if errors.As(err, &restaurantConflict) {
+		return true
+	}
+	if err != nil {
+		log.Printf("merge guest cart into user %d: %v", userID, err)
+	}
+	return false
+}
+
+// cartOwner returns whose cart the request works on. A signed-in user always
+// uses their own cart; otherwise a valid guest session gets a guest cart and
+// userID 0.
+func (r *Resolver) cartOwner(ctx context.Context) (redis.CartOwner, uint, error) {
+	if uid, ok := middleware.UserIDFromCtx(ctx); ok {
+		return redis.UserCart(uid), uid, nil
+	}
+	if gid, ok := middleware.GuestIDFromCtx(ctx); ok {
+		return redis.GuestCart(gid), 0, nil
+	}
+	return "", 0, errors.New("unauthorized: sign in or start a guest session")
+}
+
+// validateCartLine checks that a product can be put in the cart at qty
+func (r *Resolver) validateCartLine(ctx context.Context, productID uint, qty int) (*models.Product, error) {
+	if qty < 1 {
+		return nil, fmt.Errorf("quantity must be at least 1")
+	}
+	if r.MaxCartQuantity > 0 && qty > r.MaxCartQuantity {
+		return nil, fmt.Errorf("you can add at most %d of an item", r.MaxCartQuantity)
+	}
+
+	var product models.Product
+	if err := r.DB.WithContext(ctx).First(&product, productID).Error; err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	if !product.IsAvailable || product.Stock == 0 {
+		return nil, fmt.Errorf("%s is out of stock", product.Name)
+	}
+	if qty > product.Stock {
+		return nil, fmt.Errorf("only %d of %s left", product.Stock, product.Name)
+	}
+
+	closed, err := r.Pricing.ClosedRestaurants(r.DB.WithContext(ctx), []uint{product.AdminID})
+	if err != nil {
+		return nil, err
+	}
+	if closed[product.AdminID] {
+		return nil, fmt.Errorf("the restaurant selling %s is closed", product.Name)
+	}
+	return &product, nil
+}
+
+// lineProducts loads the live products behind cart or saved lines, and which
+// of their restaurants are closed
+func (r *Resolver) lineProducts(db *gorm.DB, ids []uint) (map[uint]*models.Product, map[uint]bool, error) {
+	var products []models.Product
+	if len(ids) > 0 {
+		if err := db.Where("id IN ?", ids).Find(&products).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to load cart products: %v", err)
 		}
 	}
+	byID := map[uint]*models.Product{}
+	adminIDs := []uint{}
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+		adminIDs = append(adminIDs, products[i].AdminID)
+	}
+	closed, err := r.Pricing.ClosedRestaurants(db, adminIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check restaurants: %v", err)
+	}
+	return byID, closed, nil
+}
+
+// lineWarnings checks qty units of a product against its live row: price
+// drift since addedPrice (zero when unknown), stock, availability and whether
+// its restaurant is open
+func lineWarnings(product *models.Product, addedPrice models.Money, qty int, closed bool) []*gql.CartWarning {
+	pid := fmt.Sprint(product.ID)
+	warnings := []*gql.CartWarning{}
+	warn := func(code gql.CartWarningCode, msg string) {
+		warnings = append(warnings, &gql.CartWarning{ProductID: pid, Code: code, Message: msg})
+	}
 
-	redis.SetCart(ctx, userID, newCart)
-	return r.buildCart(ctx, newCart)
+	if addedPrice.Amount > 0 && addedPrice.Amount != product.Price.Amount {
+		warn(gql.CartWarningCodePriceChanged,
+			fmt.Sprintf("%s now costs %s (was %s)", product.Name, product.Price, addedPrice))
+	}
+	switch {
+	case product.Stock == 0:
+		warn(gql.CartWarningCodeOutOfStock, fmt.Sprintf("%s is out of stock", product.Name))
+	case !product.IsAvailable:
+		warn(gql.CartWarningCodeUnavailable, fmt.Sprintf("%s is currently unavailable", product.Name))
+	case qty > product.Stock:
+		warn(gql.CartWarningCodeInsufficientStock,
+			fmt.Sprintf("only %d of %s left", product.Stock, product.Name))
+	}
+	if closed {
+		warn(gql.CartWarningCodeRestaurantClosed,
+			fmt.Sprintf("the restaurant selling %s is closed", product.Name))
+	}
+	return warnings
 }
 
-func (r *queryResolver) MyCart(ctx context.Context) (*gql.Cart, error) {
-	uid, ok := middleware.UserIDFromCtx(ctx)
-	if !ok {
-		return nil, fmt.Errorf("unauthorized: no user ID in context (myCart)")
+func removedWarning(productID uint) *gql.CartWarning {
+	return &gql.CartWarning{
+		ProductID: fmt.Sprint(productID),
+		Code:      gql.CartWarningCodeRemoved,
+		Message:   "This item is no longer on the menu; remove it to continue",
 	}
-	userID := uid
+}
 
-	cart, _ := redis.GetCart(ctx, userID)
-	return r.buildCart(ctx, cart)
+// blocksCheckout reports whether any warning is more than a price change
+func blocksCheckout(warnings []*gql.CartWarning) bool {
+	for _, w := range warnings {
+		if w.Code != gql.CartWarningCodePriceChanged {
+			return true
+		}
+	}
+	return false
 }
 
-func (r *Resolver) buildCart(ctx context.Context, cart []models.CartItem) (*gql.Cart, error) {
-	var gqlItems []*gql.CartItem
-	var total float64
+// buildCart prices the cart against live products. Nothing is dropped
+// silently: anything that changed since it was added comes back as a warning.
+func (r *Resolver) buildCart(ctx context.Context, owner redis.CartOwner, userID uint, cart []models.CartItem) (*gql.Cart, error) {
+	db := r.DB.WithContext(ctx)
+	gqlItems := []*gql.CartItem{}
+	warnings := []*gql.CartWarning{}
+	var lines []pricing.Line
+	total := models.NewMoney(0)
+	canCheckout := len(cart) > 0
+
+	ids := make([]uint, 0, len(cart))
+	for _, item := range cart {
+		ids = append(ids, item.ProductID)
+	}
+	byID, closed, err := r.lineProducts(db, ids)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, item := range cart {
-		var product models.Product
-		if err := r.DB.First(&product, item.ProductID).Error; err != nil {
-			continue // skip missing
+		product, ok := byID[item.ProductID]
+		if !ok {
+			warnings = append(warnings, removedWarning(item.ProductID))
+			canCheckout = false
+			continue
 		}
-		total += product.Price * float64(item.Quantity)
-		gqlItems = append(gqlItems, &gql.CartItem{
-			Product:  mapProductToGQL(&product),
+
+		total = total.Add(product.Price.Mul(item.Quantity))
+		gqlItem := &gql.CartItem{
+			Product:  mapProductToGQL(product),
 			Quantity: item.Quantity,
+			Warnings: lineWarnings(product, item.Price, item.Quantity, closed[product.AdminID]),
+		}
+		// Older carts have no stored price; nothing to compare against
+		if item.Price.Amount > 0 {
+			addedPrice := item.Price
+			gqlItem.AddedPrice = &addedPrice
+			gqlItem.PriceChanged = item.Price.Amount != product.Price.Amount
+		}
+		warnings = append(warnings, gqlItem.Warnings...)
+		if blocksCheckout(gqlItem.Warnings) {
+			canCheckout = false
+		}
+
+		gqlItems = append(gqlItems, gqlItem)
+		lines = append(lines, pricing.Line{
+			ProductID:    product.ID,
+			Name:         product.Name,
+			Category:     product.Category,
+			RestaurantID: product.AdminID,
+			UnitPrice:    product.Price,
+			Quantity:     item.Quantity,
 		})
 	}
 
+	bill, err := r.Pricing.Quote(db, userID, lines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price cart: %v", err)
+	}
+
+	// An applied coupon is re-checked every time; if it stopped applying the
+	// cart says why instead of silently dropping it
+	var couponCode, couponError *string
+	code, err := redis.GetCartCoupon(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("read cart coupon: %w", err)
+	}
+	if code != "" {
+		couponCode = &code
+		coupon, discount, err := r.Coupons.Evaluate(db, userID, code, bill, 0)
+		if err != nil {
+			couponError = strPtr(err.Error())
+		} else {
+			bill = pricing.WithDiscount(bill, coupon.Code, discount)
+		}
+	}
+
+	var restaurantID *string
+	if len(lines) > 0 {
+		restaurantID = strPtr(fmt.Sprint(lines[0].RestaurantID))
+	}
+
 	return &gql.Cart{
-		Items: gqlItems,
-		Total: total,
+		RestaurantID: restaurantID,
+		Items:        gqlItems,
+		Total:        total,
+		Bill:         mapBillToGQL(bill),
+		Coupon:       couponCode,
+		CouponError:  couponError,
+		Warnings:     warnings,
+		CanCheckout:  canCheckout,
 	}, nil
 }
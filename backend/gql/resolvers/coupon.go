@@ -0,0 +1,173 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/middleware"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+	"swiggy-clone/backend/services"
+)
+
+// ApplyCoupon attaches a promo code to the cart if it gives a discount right now
+func (r *mutationResolver) ApplyCoupon(ctx context.Context, code string) (*gql.Cart, error) {
+	owner, uid, err := r.cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	code = services.NormalizeCode(code)
+	if code == "" {
+		return nil, errors.New("coupon code is required")
+	}
+
+	if err := redis.SetCartCoupon(ctx, owner, code); err != nil {
+		return nil, err
+	}
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.buildCart(ctx, owner, uid, cart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't keep a coupon that doesn't apply; the user gets the reason instead
+	if out.CouponError != nil {
+		if err := redis.ClearCartCoupon(ctx, owner); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(*out.CouponError)
+	}
+	return out, nil
+}
+
+// RemoveCoupon takes the promo code off the cart
+func (r *mutationResolver) RemoveCoupon(ctx context.Context) (*gql.Cart, error) {
+	owner, uid, err := r.cartOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := redis.ClearCartCoupon(ctx, owner); err != nil {
+		return nil, err
+	}
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, uid, cart)
+}
+
+// CreateCoupon lets an admin create a coupon for their own restaurant
+func (r *mutationResolver) CreateCoupon(ctx context.Context, input gql.CouponInput) (*gql.Coupon, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &models.Coupon{
+		Code:         input.Code,
+		Type:         models.CouponType(input.Type),
+		FlatAmount:   models.NewMoney(0),
+		MinOrder:     models.NewMoney(0),
+		MaxDiscount:  models.NewMoney(0),
+		RestaurantID: &uid,
+		ValidFrom:    input.ValidFrom,
+		ValidUntil:   input.ValidUntil,
+		Active:       true,
+		CreatedBy:    uid,
+	}
+	if input.Description != nil {
+		c.Description = *input.Description
+	}
+	if input.PercentOff != nil {
+		bps, err := percentToBps(*input.PercentOff)
+		if err != nil {
+			return nil, err
+		}
+		c.PercentBps = bps
+	}
+	if input.FlatAmount != nil {
+		c.FlatAmount = *input.FlatAmount
+	}
+	if input.MinOrder != nil {
+		c.MinOrder = *input.MinOrder
+	}
+	if input.MaxDiscount != nil {
+		c.MaxDiscount = *input.MaxDiscount
+	}
+	if input.UsageLimit != nil {
+		c.UsageLimit = *input.UsageLimit
+	}
+	if input.PerUserLimit != nil {
+		c.PerUserLimit = *input.PerUserLimit
+	}
+	if input.FirstOrderOnly != nil {
+		c.FirstOrderOnly = *input.FirstOrderOnly
+	}
+
+	if err := r.Coupons.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return mapCouponToGQL(c), nil
+}
+
+// SetCouponActive switches one of the admin's coupons on or off
+func (r *mutationResolver) SetCouponActive(ctx context.Context, id string, active bool) (*gql.Coupon, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid coupon ID")
+	}
+	c, err := r.Coupons.SetActive(ctx, uid, uint(cid), active)
+	if err != nil {
+		return nil, err
+	}
+	return mapCouponToGQL(c), nil
+}
+
+// MyCoupons lists the coupons the admin created
+func (r *queryResolver) MyCoupons(ctx context.Context) ([]*gql.Coupon, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	coupons, err := r.Coupons.ByCreator(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch coupons: %v", err)
+	}
+
+	out := []*gql.Coupon{}
+	for i := range coupons {
+		out = append(out, mapCouponToGQL(&coupons[i]))
+	}
+	return out, nil
+}
+
+func mapCouponToGQL(c *models.Coupon) *gql.Coupon {
+	return &gql.Coupon{
+		ID:             fmt.Sprint(c.ID),
+		Code:           c.Code,
+		Description:    c.Description,
+		Type:           gql.CouponType(c.Type),
+		PercentOff:     float64(c.PercentBps) / 100,
+		FlatAmount:     c.FlatAmount,
+		MinOrder:       c.MinOrder,
+		MaxDiscount:    c.MaxDiscount,
+		UsageLimit:     c.UsageLimit,
+		PerUserLimit:   c.PerUserLimit,
+		UsedCount:      c.UsedCount,
+		FirstOrderOnly: c.FirstOrderOnly,
+		RestaurantID:   uintPtrToString(c.RestaurantID),
+		ValidFrom:      c.ValidFrom,
+		ValidUntil:     c.ValidUntil,
+		Active:         c.Active,
+	}
+}
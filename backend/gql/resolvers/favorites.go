@@ -0,0 +1,321 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/middleware"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+)
+
+// SaveForLater moves a line out of the cart onto the saved list
+func (r *mutationResolver) SaveForLater(ctx context.Context, productID string) (*gql.Cart, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized: sign in to save items for later")
+	}
+	pid, err := strconv.Atoi(productID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID")
+	}
+	owner := redis.UserCart(uid)
+
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	var line *models.CartItem
+	for i := range cart {
+		if cart[i].ProductID == uint(pid) {
+			line = &cart[i]
+		}
+	}
+	if line == nil {
+		return nil, redis.ErrNotInCart
+	}
+
+	// Saved first, so a failure in between leaves the item in both places
+	// rather than in neither
+	if _, err := r.Favorites.SaveForLater(ctx, uid, *line); err != nil {
+		return nil, err
+	}
+	if err := redis.RemoveFromCart(ctx, owner, uint(pid)); err != nil {
+		return nil, err
+	}
+
+	cart, err = redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, uid, cart)
+}
+
+// MoveToCart puts a saved line back in the cart, with the same checks as addToCart
+func (r *mutationResolver) MoveToCart(ctx context.Context, productID string) (*gql.Cart, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	pid, err := strconv.Atoi(productID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID")
+	}
+	owner := redis.UserCart(uid)
+
+	saved, err := r.Favorites.SavedItem(ctx, uid, uint(pid))
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	newQty := saved.Quantity
+	for _, item := range cart {
+		if item.ProductID == saved.ProductID {
+			newQty += item.Quantity
+		}
+	}
+	product, err := r.validateCartLine(ctx, saved.ProductID, newQty)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = redis.AddToCart(ctx, owner, models.CartItem{
+		ProductID: product.ID,
+		AdminID:   product.AdminID,
+		Price:     product.Price,
+	}, saved.Quantity, r.MaxCartQuantity)
+	var conflict *redis.RestaurantConflictError
+	if errors.As(err, &conflict) {
+		return nil, r.restaurantConflictError(ctx, conflict)
+	}
+	if errors.Is(err, redis.ErrCartQuantityLimit) {
+		return nil, fmt.Errorf("you can add at most %d of an item", r.MaxCartQuantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Favorites.RemoveSaved(ctx, uid, saved.ProductID); err != nil {
+		return nil, err
+	}
+
+	cart, err = redis.GetCart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return r.buildCart(ctx, owner, uid, cart)
+}
+
+// AddFavorite marks a dish or restaurant as a favourite
+func (r *mutationResolver) AddFavorite(ctx context.Context, kind gql.FavoriteKind, id string) (*gql.Favorite, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	target, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID")
+	}
+	fav, err := r.Favorites.AddFavorite(ctx, uid, models.FavoriteKind(kind), uint(target))
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.mapFavorites(ctx, []models.Favorite{*fav})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// RemoveFavorite unmarks a dish or restaurant
+func (r *mutationResolver) RemoveFavorite(ctx context.Context, kind gql.FavoriteKind, id string) (bool, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return false, errors.New("unauthorized")
+	}
+	target, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid ID")
+	}
+	if err := r.Favorites.RemoveFavorite(ctx, uid, models.FavoriteKind(kind), uint(target)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *queryResolver) SavedForLater(ctx context.Context) ([]*gql.SavedItem, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	return r.savedItems(ctx, uid)
+}
+
+func (r *queryResolver) Favorites(ctx context.Context, kind *gql.FavoriteKind) ([]*gql.Favorite, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	return r.favorites(ctx, uid, kind)
+}
+
+// The lists are private, so they only resolve on the caller's own user
+
+func (r *userResolver) SavedForLater(ctx context.Context, obj *gql.User) ([]*gql.SavedItem, error) {
+	uid, err := r.ownUser(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return r.savedItems(ctx, uid)
+}
+
+func (r *userResolver) Favorites(ctx context.Context, obj *gql.User, kind *gql.FavoriteKind) ([]*gql.Favorite, error) {
+	uid, err := r.ownUser(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return r.favorites(ctx, uid, kind)
+}
+
+func (r *userResolver) ownUser(ctx context.Context, obj *gql.User) (uint, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok || obj.ID != fmt.Sprint(uid) {
+		return 0, errors.New("forbidden: you can only see your own lists")
+	}
+	return uid, nil
+}
+
+func (r *Resolver) User() gql.UserResolver { return &userResolver{r} }
+
+type userResolver struct{ *Resolver }
+
+// savedItems checks each saved line against the live product like buildCart does
+func (r *Resolver) savedItems(ctx context.Context, uid uint) ([]*gql.SavedItem, error) {
+	saved, err := r.Favorites.SavedItems(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch saved items: %v", err)
+	}
+	ids := make([]uint, 0, len(saved))
+	for _, s := range saved {
+		ids = append(ids, s.ProductID)
+	}
+	byID, closed, err := r.lineProducts(r.DB.WithContext(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []*gql.SavedItem{}
+	for _, s := range saved {
+		item := &gql.SavedItem{
+			ID:         fmt.Sprint(s.ID),
+			ProductID:  fmt.Sprint(s.ProductID),
+			Quantity:   s.Quantity,
+			SavedPrice: s.Price,
+			SavedAt:    s.UpdatedAt,
+		}
+		product, ok := byID[s.ProductID]
+		if !ok {
+			item.Warnings = []*gql.CartWarning{removedWarning(s.ProductID)}
+			out = append(out, item)
+			continue
+		}
+		price := product.Price
+		item.Product = mapProductToGQL(product)
+		item.CurrentPrice = &price
+		item.PriceChanged = s.Price.Amount > 0 && s.Price.Amount != price.Amount
+		item.Warnings = lineWarnings(product, s.Price, s.Quantity, closed[product.AdminID])
+		item.Available = !blocksCheckout(item.Warnings)
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+func (r *Resolver) favorites(ctx context.Context, uid uint, kind *gql.FavoriteKind) ([]*gql.Favorite, error) {
+	var k models.FavoriteKind
+	if kind != nil {
+		k = models.FavoriteKind(*kind)
+	}
+	favs, err := r.Favorites.Favorites(ctx, uid, k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch favourites: %v", err)
+	}
+	return r.mapFavorites(ctx, favs)
+}
+
+// mapFavorites resolves dishes like cart lines (one unit, no remembered price)
+// and restaurants by whether they are open
+func (r *Resolver) mapFavorites(ctx context.Context, favs []models.Favorite) ([]*gql.Favorite, error) {
+	db := r.DB.WithContext(ctx)
+	productIDs, adminIDs := []uint{}, []uint{}
+	for _, f := range favs {
+		if f.Kind == models.FavoriteProduct {
+			productIDs = append(productIDs, f.TargetID)
+		} else {
+			adminIDs = append(adminIDs, f.TargetID)
+		}
+	}
+	byID, closed, err := r.lineProducts(db, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	restaurants := map[uint]*models.Restaurant{}
+	admins := map[uint]bool{}
+	restaurantClosed := map[uint]bool{}
+	if len(adminIDs) > 0 {
+		var rows []models.Restaurant
+		if err := db.Where("admin_id IN ?", adminIDs).Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to load restaurants: %v", err)
+		}
+		for i := range rows {
+			restaurants[rows[i].AdminID] = &rows[i]
+		}
+		var ids []uint
+		if err := db.Model(&models.User{}).Where("id IN ? AND role = ?", adminIDs, "admin").Pluck("id", &ids).Error; err != nil {
+			return nil, fmt.Errorf("failed to load restaurants: %v", err)
+		}
+		for _, id := range ids {
+			admins[id] = true
+		}
+		if restaurantClosed, err = r.Pricing.ClosedRestaurants(db, adminIDs); err != nil {
+			return nil, fmt.Errorf("failed to check restaurants: %v", err)
+		}
+	}
+
+	out := []*gql.Favorite{}
+	for _, f := range favs {
+		fav := &gql.Favorite{
+			ID:        fmt.Sprint(f.ID),
+			Kind:      gql.FavoriteKind(f.Kind),
+			TargetID:  fmt.Sprint(f.TargetID),
+			Warnings:  []*gql.CartWarning{},
+			CreatedAt: f.CreatedAt,
+		}
+		switch f.Kind {
+		case models.FavoriteProduct:
+			product, ok := byID[f.TargetID]
+			if !ok {
+				fav.Warnings = []*gql.CartWarning{removedWarning(f.TargetID)}
+				break
+			}
+			price := product.Price
+			fav.Product = mapProductToGQL(product)
+			fav.CurrentPrice = &price
+			fav.Warnings = lineWarnings(product, models.Money{}, 1, closed[product.AdminID])
+			fav.Available = !blocksCheckout(fav.Warnings)
+		case models.FavoriteRestaurant:
+			if rest := restaurants[f.TargetID]; rest != nil {
+				fav.Restaurant = mapRestaurantToGQL(rest)
+			}
+			fav.Available = admins[f.TargetID] && !restaurantClosed[f.TargetID]
+		}
+		out = append(out, fav)
+	}
+	return out, nil
+}
@@ -3,6 +3,10 @@ package resolvers
 import (
 	"context"
 	"errors"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"swiggy-clone/backend/idempotency"
 )
 
 func GetUserIDFromCtx(ctx context.Context) (uint, error) {
@@ -18,3 +22,21 @@ func GetUserIDFromCtx(ctx context.Context) (uint, error) {
 
 	return userID, nil
 }
+
+// idempotencyError turns idempotency conflicts into GraphQL errors carrying an
+// HTTP-style 409 status so clients can tell them apart from real failures.
+func idempotencyError(err error) error {
+	code := ""
+	switch {
+	case errors.Is(err, idempotency.ErrFingerprintMismatch):
+		code = "IDEMPOTENCY_KEY_REUSED"
+	case errors.Is(err, idempotency.ErrInFlight):
+		code = "IDEMPOTENCY_IN_PROGRESS"
+	default:
+		return err
+	}
+	return &gqlerror.Error{
+		Message:    err.Error(),
+		Extensions: map[string]interface{}{"code": code, "status": 409},
+	}
+}
@@ -0,0 +1,207 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/middleware"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+)
+
+// RestockProduct adds stock to one of the admin's products
+func (r *mutationResolver) RestockProduct(ctx context.Context, productID string, quantity int, note *string) (*gql.Product, error) {
+	uid, p, err := r.ownProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	n := ""
+	if note != nil {
+		n = *note
+	}
+	updated, err := r.Inventory.Restock(ctx, uid, p.ID, quantity, n)
+	if err != nil {
+		return nil, err
+	}
+	redis.DelPattern(ctx, "products:*")
+
+	return mapProductToGQL(updated), nil
+}
+
+// AdjustStock applies a manual stock correction with a mandatory note
+func (r *mutationResolver) AdjustStock(ctx context.Context, productID string, delta int, note string) (*gql.Product, error) {
+	uid, p, err := r.ownProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if note == "" {
+		return nil, fmt.Errorf("a note is required for manual adjustments")
+	}
+
+	updated, err := r.Inventory.Adjust(ctx, uid, p.ID, delta, note)
+	if err != nil {
+		return nil, err
+	}
+	redis.DelPattern(ctx, "products:*")
+
+	return mapProductToGQL(updated), nil
+}
+
+// ReportPaymentFailure releases the order's stock hold straight away instead
+// of waiting for it to expire
+func (r *mutationResolver) ReportPaymentFailure(ctx context.Context, orderID string, reason *string) (bool, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return false, fmt.Errorf("unauthenticated")
+	}
+
+	var order models.Order
+	if err := r.DB.Where("id = ? AND user_id = ?", orderID, uid).First(&order).Error; err != nil {
+		return false, fmt.Errorf("order not found")
+	}
+
+	note := "payment failed"
+	if reason != nil && *reason != "" {
+		note = "payment failed: " + *reason
+	}
+	if err := r.Inventory.Release(ctx, order.ID, models.ReservationReleased, note); err != nil {
+		return false, fmt.Errorf("failed to release reservation: %v", err)
+	}
+	return true, nil
+}
+
+// InventoryLedger lists stock movements for one of the admin's products
+func (r *queryResolver) InventoryLedger(ctx context.Context, productID string, limit *int) ([]*gql.StockMovement, error) {
+	_, p, err := r.ownProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	n := 50
+	if limit != nil && *limit > 0 && *limit <= 500 {
+		n = *limit
+	}
+	movements, err := r.Inventory.Ledger(ctx, p.ID, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ledger: %v", err)
+	}
+
+	out := []*gql.StockMovement{}
+	for _, m := range movements {
+		out = append(out, &gql.StockMovement{
+			ID:         fmt.Sprint(m.ID),
+			ProductID:  fmt.Sprint(m.ProductID),
+			Delta:      m.Delta,
+			StockAfter: m.StockAfter,
+			Reason:     gql.StockMovementReason(m.Reason),
+			OrderID:    uintPtrToString(m.OrderID),
+			ActorID:    uintPtrToString(m.ActorID),
+			Note:       m.Note,
+			CreatedAt:  m.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// OrderReservations shows the stock holds for one of the user's orders
+// (the payment page uses expiresAt for its countdown)
+func (r *queryResolver) OrderReservations(ctx context.Context, orderID string) ([]*gql.StockReservation, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, fmt.Errorf("unauthenticated")
+	}
+
+	var reservations []models.StockReservation
+	if err := r.DB.Where("order_id = ? AND user_id = ?", orderID, uid).Find(&reservations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reservations: %v", err)
+	}
+
+	out := []*gql.StockReservation{}
+	for _, res := range reservations {
+		out = append(out, &gql.StockReservation{
+			ID:        fmt.Sprint(res.ID),
+			OrderID:   fmt.Sprint(res.OrderID),
+			ProductID: fmt.Sprint(res.ProductID),
+			Quantity:  res.Quantity,
+			Status:    gql.ReservationStatus(res.Status),
+			ExpiresAt: res.ExpiresAt,
+		})
+	}
+	return out, nil
+}
+
+// ownProduct loads a product and checks it belongs to the calling admin
+func (r *Resolver) ownProduct(ctx context.Context, productID string) (uint, *models.Product, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return 0, nil, errors.New("unauthorized")
+	}
+
+	var p models.Product
+	if err := r.DB.First(&p, productID).Error; err != nil {
+		return 0, nil, fmt.Errorf("product not found")
+	}
+	if p.AdminID != uid {
+		return 0, nil, errors.New("forbidden: product belongs to another admin")
+	}
+	return uid, &p, nil
+}
+
+// requireAdmin returns the caller's ID if they are an admin. The JWT carries
+// no role, so it is read from the users table.
+func (r *Resolver) requireAdmin(ctx context.Context) (uint, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return 0, errors.New("unauthorized")
+	}
+	var user models.User
+	if err := r.DB.WithContext(ctx).Select("id", "role").First(&user, uid).Error; err != nil || user.Role != "admin" {
+		return 0, errors.New("forbidden: admins only")
+	}
+	return uid, nil
+}
+
+func uintPtrToString(v *uint) *string {
+	if v == nil {
+		return nil
+	}
+	return strPtr(fmt.Sprint(*v))
+}
+
+// SetLowStockThreshold changes when low-stock alerts fire for a product
+func (r *mutationResolver) SetLowStockThreshold(ctx context.Context, productID string, threshold int) (*gql.Product, error) {
+	_, p, err := r.ownProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := r.Inventory.SetLowStockThreshold(ctx, p.ID, threshold)
+	if err != nil {
+		return nil, err
+	}
+	redis.DelPattern(ctx, "products:*")
+
+	return mapProductToGQL(updated), nil
+}
+
+// LowStockProducts lists the admin's products that need restocking
+func (r *queryResolver) LowStockProducts(ctx context.Context) ([]*gql.Product, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+
+	products, err := r.Inventory.LowStock(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch low stock products: %v", err)
+	}
+
+	out := []*gql.Product{}
+	for i := range products {
+		out = append(out, mapProductToGQL(&products[i]))
+	}
+	return out, nil
+}
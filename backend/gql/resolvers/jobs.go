@@ -0,0 +1,71 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/models"
+)
+
+var errNoJobStore = errors.New("failed jobs are only kept by the Postgres order queue")
+
+// FailedOrderJobs lists order jobs that ran out of retries, for the admin's orders
+func (r *queryResolver) FailedOrderJobs(ctx context.Context, includeReplayed *bool, limit *int) ([]*gql.FailedJob, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.OrderJobs == nil {
+		return nil, errNoJobStore
+	}
+	n := 50
+	if limit != nil && *limit > 0 && *limit <= 200 {
+		n = *limit
+	}
+	jobs, err := r.OrderJobs.DeadJobs(ctx, uid, includeReplayed != nil && *includeReplayed, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch failed jobs: %v", err)
+	}
+	out := make([]*gql.FailedJob, 0, len(jobs))
+	for i := range jobs {
+		out = append(out, mapFailedJob(&jobs[i]))
+	}
+	return out, nil
+}
+
+// ReplayFailedJob queues a failed order job again
+func (r *mutationResolver) ReplayFailedJob(ctx context.Context, id string) (*gql.FailedJob, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.OrderJobs == nil {
+		return nil, errNoJobStore
+	}
+	jobID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job ID")
+	}
+	job, err := r.OrderJobs.Replay(ctx, uint(jobID), uid)
+	if err != nil {
+		return nil, err
+	}
+	return mapFailedJob(job), nil
+}
+
+func mapFailedJob(j *models.DeadJob) *gql.FailedJob {
+	return &gql.FailedJob{
+		ID:         fmt.Sprint(j.ID),
+		OrderID:    fmt.Sprint(j.OrderID),
+		EventType:  j.EventType,
+		Queue:      j.Queue,
+		Attempts:   j.Attempts,
+		LastError:  j.LastError,
+		EnqueuedAt: j.EnqueuedAt,
+		FailedAt:   j.FailedAt,
+		ReplayedAt: j.ReplayedAt,
+	}
+}
@@ -0,0 +1,53 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/middleware"
+)
+
+// MyNotifications lists the current user's notifications, newest first
+func (r *queryResolver) MyNotifications(ctx context.Context, unreadOnly *bool) ([]*gql.Notification, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, fmt.Errorf("unauthenticated")
+	}
+
+	list, err := r.Notifications.List(ctx, uid, unreadOnly != nil && *unreadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notifications: %v", err)
+	}
+
+	out := []*gql.Notification{}
+	for _, n := range list {
+		out = append(out, &gql.Notification{
+			ID:        fmt.Sprint(n.ID),
+			Kind:      gql.NotificationKind(n.Kind),
+			Message:   n.Message,
+			ProductID: uintPtrToString(n.ProductID),
+			Read:      n.ReadAt != nil,
+			CreatedAt: n.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// MarkNotificationRead marks one of the current user's notifications as read
+func (r *mutationResolver) MarkNotificationRead(ctx context.Context, id string) (bool, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return false, fmt.Errorf("unauthenticated")
+	}
+
+	nid, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid notification ID")
+	}
+	if err := r.Notifications.MarkRead(ctx, uid, uint(nid)); err != nil {
+		return false, fmt.Errorf("failed to mark notification read: %v", err)
+	}
+	return true, nil
+}
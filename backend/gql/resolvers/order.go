@@ -4,12 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"swiggy-clone/backend/gql"
 	"swiggy-clone/backend/middleware"
 	"swiggy-clone/backend/models"
-
-	"github.com/lib/pq"
+	"swiggy-clone/backend/redis"
+	"swiggy-clone/backend/services"
 )
 
 // GetOrderHistory fetches all orders for the current user with full product details
@@ -30,60 +31,8 @@ func (r *queryResolver) GetOrderHistory(ctx context.Context) ([]*gql.Order, erro
 	}
 
 	var gqlOrders []*gql.Order
-
-	for _, o := range orders {
-		// Unmarshal stored JSON product snapshots
-		var snapshots []map[string]interface{}
-		if len(o.Products) > 0 {
-			if err := json.Unmarshal(o.Products, &snapshots); err != nil {
-				fmt.Println("❌ failed to unmarshal products JSON:", err)
-				snapshots = []map[string]interface{}{}
-			}
-		}
-
-		// Convert to gql.ProductItem slices
-		productItems := buildGQLProductItems(snapshots)
-
-		// Convert OrderItems for GQL
-		var gqlOrderItems []*gql.OrderItem
-		for _, item := range o.Items {
-			var product *gql.Product
-			// Try to match item.ProductID with snapshot for richer details
-			for _, snap := range snapshots {
-				if fmt.Sprint(snap["id"]) == fmt.Sprint(item.ProductID) {
-					imgStr := fmt.Sprint(snap["image"])
-					qStr := fmt.Sprintf("%v", snap["quantity"])
-					product = &gql.Product{
-						ID:       fmt.Sprint(snap["id"]),
-						Name:     snap["name"].(string),
-						Price:    snap["price"].(float64),
-						Image:    &imgStr,
-						Quantity: &qStr,
-					}
-					break
-				}
-			}
-
-			gqlOrderItems = append(gqlOrderItems, &gql.OrderItem{
-				ProductID:       fmt.Sprint(item.ProductID),
-				Quantity:        item.Quantity,
-				PriceAtPurchase: item.PriceAtPurchase,
-				Product:         product,
-			})
-		}
-
-		// Build the final GQL order
-		gqlOrders = append(gqlOrders, &gql.Order{
-			ID:             fmt.Sprint(o.ID),
-			UserID:         fmt.Sprint(o.UserID),
-			Products:       productItems,
-			ProductAdmins:  o.ProductAdmins,
-			TotalPrice:     o.Total,
-			Status:         gql.OrderStatus(o.Status),
-			PlacedAt:       o.PlacedAt,
-			Items:          gqlOrderItems,
-			IdempotencyKey: o.IdempotencyKey,
-		})
+	for i := range orders {
+		gqlOrders = append(gqlOrders, mapOrderToGQL(&orders[i]))
 	}
 
 	return gqlOrders, nil
@@ -97,6 +46,7 @@ func (r *queryResolver) GetAdminOrders(ctx context.Context) ([]*gql.Order, error
 	// 1️⃣ Fetch orders where admin's ID is in product_admins[]
 	var orders []models.Order
 	if err := r.DB.
+		Preload("Items").
 		Where("? = ANY(product_admins)", fmt.Sprint(uid)).
 		Order("placed_at DESC").
 		Find(&orders).Error; err != nil {
@@ -105,30 +55,274 @@ func (r *queryResolver) GetAdminOrders(ctx context.Context) ([]*gql.Order, error
 
 	// 2️⃣ Convert to gql.Order format
 	var gqlOrders []*gql.Order
-	for _, o := range orders {
-		// Unmarshal product snapshots (stored JSON)
-		var snapshots []map[string]interface{}
-		if len(o.Products) > 0 {
-			if err := json.Unmarshal(o.Products, &snapshots); err != nil {
-				fmt.Println("❌ failed to unmarshal products:", err)
-				snapshots = []map[string]interface{}{}
-			}
+	for i := range orders {
+		gqlOrders = append(gqlOrders, mapOrderToGQL(&orders[i]))
+	}
+
+	return gqlOrders, nil
+}
+
+// Reorder rebuilds the cart from one of the user's orders. Whatever can't be
+// re-added right now comes back in skipped with the reason; partial stock
+// re-adds what is left.
+func (r *mutationResolver) Reorder(ctx context.Context, orderID string) (*gql.ReorderResult, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	oid, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+
+	var order models.Order
+	if err := r.DB.WithContext(ctx).Preload("Items").
+		Where("id = ? AND user_id = ?", oid, uid).
+		First(&order).Error; err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+
+	// Names of products that have since been deleted come from the snapshot
+	names := map[uint]string{}
+	var snapshots []models.ProductSnapshot
+	if len(order.Products) > 0 && json.Unmarshal(order.Products, &snapshots) == nil {
+		for _, s := range snapshots {
+			names[s.ID] = s.Name
 		}
+	}
 
-		// Convert products snapshot to gql type
-		productItems := buildGQLProductItems(snapshots)
-
-		gqlOrders = append(gqlOrders, &gql.Order{
-			ID:             fmt.Sprint(o.ID),
-			UserID:         fmt.Sprint(o.UserID),
-			Products:       productItems,
-			ProductAdmins:  pq.StringArray(o.ProductAdmins),
-			TotalPrice:     o.Total,
-			Status:         gql.OrderStatus(o.Status),
-			PlacedAt:       o.PlacedAt,
-			IdempotencyKey: o.IdempotencyKey,
+	ids := make([]uint, 0, len(order.Items))
+	for _, it := range order.Items {
+		ids = append(ids, it.ProductID)
+	}
+	byID, closed, err := r.lineProducts(r.DB.WithContext(ctx), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := []*gql.ReorderSkippedItem{}
+	skip := func(productID uint, qty int, reason gql.ReorderSkipReason, msg string) {
+		name := names[productID]
+		if p, ok := byID[productID]; ok {
+			name = p.Name
+		}
+		skipped = append(skipped, &gql.ReorderSkippedItem{
+			ProductID: fmt.Sprint(productID),
+			Name:      name,
+			Quantity:  qty,
+			Reason:    reason,
+			Message:   msg,
 		})
 	}
 
-	return gqlOrders, nil
+	cart := []models.CartItem{}
+	index := map[uint]int{}
+	var restaurant uint
+	for _, it := range order.Items {
+		product, ok := byID[it.ProductID]
+		switch {
+		case !ok:
+			skip(it.ProductID, it.Quantity, gql.ReorderSkipReasonRemoved, "This item is no longer on the menu")
+			continue
+		case restaurant != 0 && product.AdminID != restaurant:
+			skip(it.ProductID, it.Quantity, gql.ReorderSkipReasonOtherRestaurant,
+				fmt.Sprintf("%s is from another restaurant; a cart holds one restaurant", product.Name))
+			continue
+		case closed[product.AdminID]:
+			skip(it.ProductID, it.Quantity, gql.ReorderSkipReasonRestaurantClosed,
+				fmt.Sprintf("the restaurant selling %s is closed", product.Name))
+			continue
+		case product.Stock == 0:
+			skip(it.ProductID, it.Quantity, gql.ReorderSkipReasonOutOfStock, fmt.Sprintf("%s is out of stock", product.Name))
+			continue
+		case !product.IsAvailable:
+			skip(it.ProductID, it.Quantity, gql.ReorderSkipReasonUnavailable,
+				fmt.Sprintf("%s is currently unavailable", product.Name))
+			continue
+		}
+		restaurant = product.AdminID
+
+		// The same product can appear twice in older orders
+		i, ok := index[product.ID]
+		if !ok {
+			i = len(cart)
+			index[product.ID] = i
+			cart = append(cart, models.CartItem{ProductID: product.ID, AdminID: product.AdminID, Price: it.PriceAtPurchase})
+		}
+		cart[i].Quantity += it.Quantity
+	}
+
+	// Clamp to what is in stock and to the cart limit
+	for i := range cart {
+		product := byID[cart[i].ProductID]
+		if cart[i].Quantity > product.Stock {
+			skip(product.ID, cart[i].Quantity-product.Stock, gql.ReorderSkipReasonInsufficientStock,
+				fmt.Sprintf("only %d of %s left", product.Stock, product.Name))
+			cart[i].Quantity = product.Stock
+		}
+		if r.MaxCartQuantity > 0 && cart[i].Quantity > r.MaxCartQuantity {
+			skip(product.ID, cart[i].Quantity-r.MaxCartQuantity, gql.ReorderSkipReasonQuantityLimit,
+				fmt.Sprintf("you can add at most %d of an item", r.MaxCartQuantity))
+			cart[i].Quantity = r.MaxCartQuantity
+		}
+	}
+
+	// Nothing re-addable leaves the current cart alone
+	owner := redis.UserCart(uid)
+	if len(cart) == 0 {
+		if cart, err = redis.GetCart(ctx, owner); err != nil {
+			return nil, err
+		}
+	} else if err := redis.ReplaceCart(ctx, owner, cart); err != nil {
+		return nil, err
+	}
+	out, err := r.buildCart(ctx, owner, uid, cart)
+	if err != nil {
+		return nil, err
+	}
+	return &gql.ReorderResult{Cart: out, Skipped: skipped}, nil
+}
+
+// CancelOrder cancels one of the customer's own orders under the refund policy
+func (r *mutationResolver) CancelOrder(ctx context.Context, orderID string, reason string) (*gql.Order, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	oid, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to cancel an order")
+	}
+	order, err := r.Orders.Cancel(ctx, uint(oid), uid, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.DB.WithContext(ctx).Preload("Items").First(order, order.ID).Error; err != nil {
+		return nil, err
+	}
+	return mapOrderToGQL(order), nil
+}
+
+// UpdateOrderStatus lets the restaurant move its order forward one step.
+// Accepting, rejecting and cancelling have their own rules and aren't done here.
+func (r *mutationResolver) UpdateOrderStatus(ctx context.Context, orderID string, status gql.OrderStatus, reason *string) (*gql.Order, error) {
+	to := models.OrderStatus(status)
+	switch to {
+	case models.OrderAccepted:
+		return nil, fmt.Errorf("use acceptOrder to accept an order")
+	case models.OrderRejected:
+		return nil, fmt.Errorf("use rejectOrder to reject an order")
+	case models.OrderCancelled:
+		return nil, fmt.Errorf("orders can't be set to %s this way", to)
+	}
+	why := ""
+	if reason != nil {
+		why = *reason
+	}
+	return r.actOnAdminOrder(ctx, orderID, func(uid uint, order *models.Order) error {
+		_, err := r.Orders.Transition(ctx, order.ID, to, services.AdminActor(uid), why)
+		return err
+	})
+}
+
+// AcceptOrder confirms a paid order and says how long it will take
+func (r *mutationResolver) AcceptOrder(ctx context.Context, orderID string, prepMinutes int) (*gql.Order, error) {
+	return r.actOnAdminOrder(ctx, orderID, func(uid uint, order *models.Order) error {
+		_, err := r.Orders.Accept(ctx, order.ID, services.AdminActor(uid), prepMinutes)
+		return err
+	})
+}
+
+// RejectOrder turns an order down; stock goes back and payments are refunded
+func (r *mutationResolver) RejectOrder(ctx context.Context, orderID string, reason string) (*gql.Order, error) {
+	return r.actOnAdminOrder(ctx, orderID, func(uid uint, order *models.Order) error {
+		_, err := r.Orders.Reject(ctx, order.ID, services.AdminActor(uid), reason)
+		return err
+	})
+}
+
+// MarkOrderReady marks an accepted order ready for pickup
+func (r *mutationResolver) MarkOrderReady(ctx context.Context, orderID string) (*gql.Order, error) {
+	return r.actOnAdminOrder(ctx, orderID, func(uid uint, order *models.Order) error {
+		_, err := r.Orders.Advance(ctx, order.ID, models.OrderReady, services.AdminActor(uid), "ready for pickup")
+		return err
+	})
+}
+
+// MarkOrderDelivered closes out an order once it has reached the customer
+func (r *mutationResolver) MarkOrderDelivered(ctx context.Context, orderID string) (*gql.Order, error) {
+	return r.actOnAdminOrder(ctx, orderID, func(uid uint, order *models.Order) error {
+		_, err := r.Orders.Advance(ctx, order.ID, models.OrderDelivered, services.AdminActor(uid), "delivered")
+		return err
+	})
 }
+
+// actOnAdminOrder runs act for an admin on one of their orders and returns
+// the order as it is afterwards
+func (r *Resolver) actOnAdminOrder(ctx context.Context, orderID string, act func(uid uint, order *models.Order) error) (*gql.Order, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := r.adminOrder(ctx, uid, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := act(uid, order); err != nil {
+		return nil, err
+	}
+	if err := r.DB.WithContext(ctx).Preload("Items").First(order, order.ID).Error; err != nil {
+		return nil, err
+	}
+	return mapOrderToGQL(order), nil
+}
+
+// adminOrder loads an order that includes the admin's products
+func (r *Resolver) adminOrder(ctx context.Context, adminID uint, orderID string) (*models.Order, error) {
+	oid, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+	var order models.Order
+	if err := r.DB.WithContext(ctx).
+		Where("id = ? AND ? = ANY(product_admins)", oid, fmt.Sprint(adminID)).
+		First(&order).Error; err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	return &order, nil
+}
+
+func (r *orderResolver) StatusHistory(ctx context.Context, obj *gql.Order) ([]*gql.OrderStatusChange, error) {
+	oid, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+	history, err := r.Orders.History(ctx, uint(oid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch order history: %v", err)
+	}
+
+	out := []*gql.OrderStatusChange{}
+	for _, h := range history {
+		change := &gql.OrderStatusChange{
+			To:      gql.OrderStatus(h.ToStatus),
+			Actor:   gql.OrderActor(h.ActorType),
+			ActorID: uintPtrToString(h.ActorID),
+			Reason:  h.Reason,
+			At:      h.CreatedAt,
+		}
+		if h.FromStatus != "" {
+			from := gql.OrderStatus(h.FromStatus)
+			change.From = &from
+		}
+		out = append(out, change)
+	}
+	return out, nil
+}
+
+func (r *Resolver) Order() gql.OrderResolver { return &orderResolver{r} }
+
+type orderResolver struct{ *Resolver }
@@ -3,14 +3,20 @@ package resolvers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
+	"swiggy-clone/backend/events"
 	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/idempotency"
 	"swiggy-clone/backend/middleware"
 	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/services"
+
+	"gorm.io/gorm/clause"
 )
 
 // safe conversion helpers (re-use if you already have them)
@@ -56,7 +62,8 @@ import (
 
 // ✅ Mutation: CreatePayment (with debug logs)
 // CreatePaymentsFromOrder creates payment rows for an order by splitting amounts by admin.
-func (r *mutationResolver) CreatePaymentsFromOrder(ctx context.Context, orderId string, method string) ([]*gql.Payment, error) {
+// With an idempotency key, retries replay the first response instead of paying twice.
+func (r *mutationResolver) CreatePaymentsFromOrder(ctx context.Context, orderId string, method string, idempotencyKey *string) ([]*gql.Payment, error) {
 	// ensure user is authenticated (optional, but good)
 	uid, ok := middleware.UserIDFromCtx(ctx)
 
@@ -64,9 +71,26 @@ func (r *mutationResolver) CreatePaymentsFromOrder(ctx context.Context, orderId
 		return nil, fmt.Errorf("unauthenticated")
 	}
 	log.Printf(" [DEBUG] CreatePaymentsFromOrder called by user %v for order %v with method %v\n", uid, orderId, method)
-	// find order by id (orderId is string in GraphQL)
+
+	if idempotencyKey == nil || *idempotencyKey == "" || r.Idempotency == nil {
+		return r.createPaymentsFromOrder(ctx, uid, orderId, method, nil)
+	}
+
+	payments, err := idempotency.Do(ctx, r.Idempotency, "payments", uid, *idempotencyKey,
+		idempotency.Fingerprint(orderId, normalizeMethod(method)),
+		func() ([]*gql.Payment, error) {
+			return r.createPaymentsFromOrder(ctx, uid, orderId, method, idempotencyKey)
+		})
+	if err != nil {
+		return nil, idempotencyError(err)
+	}
+	return payments, nil
+}
+
+func (r *mutationResolver) createPaymentsFromOrder(ctx context.Context, uid uint, orderId string, method string, idempotencyKey *string) ([]*gql.Payment, error) {
+	// find order by id (orderId is string in GraphQL); users can only pay for their own orders
 	var order models.Order
-	if err := r.DB.Preload("Items").First(&order, orderId).Error; err != nil {
+	if err := r.DB.Preload("Items").Where("user_id = ?", uid).First(&order, orderId).Error; err != nil {
 		return nil, fmt.Errorf("order not found: %v", err)
 	}
 
@@ -80,9 +104,22 @@ func (r *mutationResolver) CreatePaymentsFromOrder(ctx context.Context, orderId
 		}
 	}
 
-	// Aggregate amount per adminId.
+	// Aggregate amount per adminId, keeping the order admins were first seen
+	// in so the split below is deterministic.
 	// We'll use snapshot adminId when available, otherwise we attempt to resolve from order.Items (if product->admin missing).
-	amountsByAdmin := map[string]float64{}
+	var adminKeys []string
+	var weights []int64
+	weightIdx := map[string]int{}
+	addWeight := func(adminKey string, amt models.Money) {
+		i, ok := weightIdx[adminKey]
+		if !ok {
+			i = len(adminKeys)
+			weightIdx[adminKey] = i
+			adminKeys = append(adminKeys, adminKey)
+			weights = append(weights, 0)
+		}
+		weights[i] += amt.Amount
+	}
 
 	// Prefer snapshots (they contain adminId and price snapshot)
 	if len(snapshots) > 0 {
@@ -91,47 +128,53 @@ func (r *mutationResolver) CreatePaymentsFromOrder(ctx context.Context, orderId
 			adminRaw := snap["adminId"]
 			adminKey := fmt.Sprint(adminRaw) // string key
 
-			price := toFloat64(snap["price"])
+			price := toMoney(snap["price"])
 			qty := toInt(snap["quantity"])
 			if qty <= 0 {
 				qty = 1
 			}
-			amountsByAdmin[adminKey] += price * float64(qty)
+			addWeight(adminKey, price.Mul(qty))
 		}
 	} else {
 		// fallback: use order.Items and load product priceAtPurchase, but we don't have adminId here.
 		// If OrderItem doesn't include adminId, we cannot split — fallback to assign full amount to first admin in ProductAdmins.
 		for _, it := range order.Items {
-			amt := it.PriceAtPurchase * float64(it.Quantity)
+			amt := it.PriceAtPurchase.Mul(it.Quantity)
 			// if product admin info not available, try to attribute to first product_admin in order.ProductAdmins
 			adminKey := "0"
 			if len(order.ProductAdmins) > 0 {
 				adminKey = order.ProductAdmins[0]
 			}
-			amountsByAdmin[adminKey] += amt
+			addWeight(adminKey, amt)
 		}
 	}
 
 	// If there are still no admins (unlikely), attribute total to "0"
-	if len(amountsByAdmin) == 0 {
-		amountsByAdmin["0"] = order.Total
+	if len(adminKeys) == 0 {
+		adminKeys, weights = []string{"0"}, []int64{1}
 	}
 
+	// Split the order total by each admin's share; the parts always add up to
+	// the total exactly, with leftover paise going to the largest remainders
+	amounts := order.Total.Allocate(weights)
+
 	// Prepare payments to insert
 	var payments []models.Payment
 	now := time.Now()
-	methodU := strings.ToUpper(strings.TrimSpace(method))
+	methodU := normalizeMethod(method)
 	status := "SUCCESS" // or "PENDING" depending on your flow
 
-	for adminKey, amt := range amountsByAdmin {
+	for i, adminKey := range adminKeys {
 		p := models.Payment{
 			UserID:    fmt.Sprint(order.UserID), // order.UserID is uint -> convert to string
 			AdminID:   adminKey,
 			OrderID:   fmt.Sprint(order.ID),
-			Amount:    amt,
+			Amount:    amounts[i],
 			Status:    status,
 			Method:    methodU,
 			CreatedAt: now,
+
+			IdempotencyKey: idempotencyKey,
 		}
 		payments = append(payments, p)
 	}
@@ -142,39 +185,96 @@ func (r *mutationResolver) CreatePaymentsFromOrder(ctx context.Context, orderId
 		return nil, fmt.Errorf("failed to start transaction: %v", tx.Error)
 	}
 
+	// The order stays locked until commit, so the reaper can't release it
+	// and the customer can't cancel it while it is being paid for
+	var current models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&current, order.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("order not found: %v", err)
+	}
+	// Stock for unpaid orders is only held for a while; once released the order is dead
+	if current.Status == models.OrderCancelled || current.Status == models.OrderRejected {
+		tx.Rollback()
+		return nil, fmt.Errorf("order %v has expired or failed; please checkout again", order.ID)
+	}
+
+	// Without a key, a second call would charge again; refuse instead. The
+	// order lock makes a concurrent second call wait here and then see these
+	// payments.
+	var paid int64
+	if err := tx.Model(&models.Payment{}).Where("order_id = ?", fmt.Sprint(order.ID)).Count(&paid).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check existing payments: %v", err)
+	}
+	if paid > 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("order %v has already been paid", order.ID)
+	}
+
 	if err := tx.Create(&payments).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to insert payments: %v", err)
 	}
 
+	// Paid: the stock hold becomes permanent
+	if err := r.Inventory.Consume(tx, order.ID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, services.ErrReservationEnded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to confirm stock reservation: %v", err)
+	}
+
+	if err := r.Events.Publish(tx, events.PaymentCaptured{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Admins:  order.ProductAdmins,
+		Amount:  order.Total,
+		Method:  methodU,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit payment transaction: %v", err)
 	}
+	r.Events.Wake()
 
 	// Build gql.Payment list for response
 	var gqlPayments []*gql.Payment
 	for _, p := range payments {
 		gqlPayments = append(gqlPayments, &gql.Payment{
-			ID:        fmt.Sprint(p.ID),
-			UserID:    p.UserID,
-			AdminID:   p.AdminID,
-			OrderID:   p.OrderID,
-			Amount:    p.Amount,
-			Status:    p.Status,
-			Method:    p.Method,
-			CreatedAt: p.CreatedAt,
+			ID:             fmt.Sprint(p.ID),
+			UserID:         p.UserID,
+			AdminID:        p.AdminID,
+			OrderID:        p.OrderID,
+			Amount:         p.Amount,
+			RefundedAmount: p.RefundedAmount,
+			Status:         p.Status,
+			Method:         p.Method,
+			CreatedAt:      p.CreatedAt,
 		})
 	}
 
 	return gqlPayments, nil
 }
 
+func normalizeMethod(method string) string {
+	return strings.ToUpper(strings.TrimSpace(method))
+}
+
 // ✅ Query: Get all payments (with logging)
 func (r *queryResolver) Payments(ctx context.Context) ([]*gql.Payment, error) {
+	// Admins see the payments made to their restaurant
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
 	log.Println(" [DEBUG] Fetching all payments from DB...")
 
 	var payments []models.Payment
-	if err := r.DB.Find(&payments).Error; err != nil {
+	if err := r.DB.Where("admin_id = ?", fmt.Sprint(uid)).Find(&payments).Error; err != nil {
 		log.Printf(" [ERROR] DB fetch failed: %v\n", err)
 		return nil, fmt.Errorf("failed to fetch payments: %v", err)
 	}
@@ -182,14 +282,15 @@ func (r *queryResolver) Payments(ctx context.Context) ([]*gql.Payment, error) {
 	var gqlPayments []*gql.Payment
 	for _, p := range payments {
 		gqlPayments = append(gqlPayments, &gql.Payment{
-			ID:        fmt.Sprint(p.ID),
-			UserID:    p.UserID,
-			AdminID:   p.AdminID,
-			OrderID:   p.OrderID,
-			Amount:    p.Amount,
-			Method:    p.Method,
-			Status:    p.Status,
-			CreatedAt: p.CreatedAt,
+			ID:             fmt.Sprint(p.ID),
+			UserID:         p.UserID,
+			AdminID:        p.AdminID,
+			OrderID:        p.OrderID,
+			Amount:         p.Amount,
+			RefundedAmount: p.RefundedAmount,
+			Method:         p.Method,
+			Status:         p.Status,
+			CreatedAt:      p.CreatedAt,
 		})
 	}
 	log.Printf("✅ [DEBUG] Found %d payments\n", len(gqlPayments))
@@ -198,6 +299,10 @@ func (r *queryResolver) Payments(ctx context.Context) ([]*gql.Payment, error) {
 
 // ✅ Query: Get single payment by ID (with logging)
 func (r *queryResolver) Payment(ctx context.Context, id string) (*gql.Payment, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
 	log.Printf(" [DEBUG] Fetching payment with ID=%v\n", id)
 
 	var p models.Payment
@@ -205,6 +310,10 @@ func (r *queryResolver) Payment(ctx context.Context, id string) (*gql.Payment, e
 		log.Printf(" [ERROR] Payment not found: %v\n", err)
 		return nil, fmt.Errorf("payment not found: %v", err)
 	}
+	// Only the payer and the restaurant that was paid may see it
+	if me := fmt.Sprint(uid); p.UserID != me && p.AdminID != me {
+		return nil, errors.New("payment not found")
+	}
 
 	return &gql.Payment{
 		ID:      fmt.Sprint(p.ID),
@@ -215,6 +324,8 @@ func (r *queryResolver) Payment(ctx context.Context, id string) (*gql.Payment, e
 		Method:  p.Method,
 		Status:  p.Status,
 
+		RefundedAmount: p.RefundedAmount,
+
 		CreatedAt: p.CreatedAt,
 	}, nil
 }
@@ -231,24 +342,8 @@ func (r *queryResolver) MyOrders(ctx context.Context) ([]*gql.Order, error) {
 	}
 
 	var gqlOrders []*gql.Order
-	for _, o := range orders {
-		// parse stored products JSON snapshot to build GQL product items
-		var snapshots []map[string]interface{}
-		if len(o.Products) > 0 {
-			_ = json.Unmarshal(o.Products, &snapshots) // ignore unmarshal error; fallback later
-		}
-
-		gqlOrders = append(gqlOrders, &gql.Order{
-			ID:             fmt.Sprint(o.ID),
-			UserID:         fmt.Sprint(o.UserID),
-			Products:       buildGQLProductItems(snapshots),
-			ProductAdmins:  o.ProductAdmins,
-			TotalPrice:     o.Total,
-			Status:         gql.OrderStatus(o.Status),
-			PlacedAt:       o.PlacedAt,
-			Items:          gqlOrderItemsFromModel(o.Items),
-			IdempotencyKey: o.IdempotencyKey,
-		})
+	for i := range orders {
+		gqlOrders = append(gqlOrders, mapOrderToGQL(&orders[i]))
 	}
 
 	if gqlOrders == nil {
@@ -0,0 +1,78 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/models"
+)
+
+// SchedulePriceChange queues a new price for one of the admin's products
+func (r *mutationResolver) SchedulePriceChange(ctx context.Context, productID string, price models.Money, effectiveAt time.Time) (*gql.PriceChange, error) {
+	uid, p, err := r.ownProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := r.Prices.Schedule(ctx, uid, p.ID, price, effectiveAt)
+	if err != nil {
+		return nil, err
+	}
+	return mapPriceChangeToGQL(row), nil
+}
+
+// CancelPriceChange drops a scheduled price change before it is applied
+func (r *mutationResolver) CancelPriceChange(ctx context.Context, id string) (*gql.PriceChange, error) {
+	pid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("invalid price change ID")
+	}
+
+	var row models.ProductPrice
+	if err := r.DB.First(&row, pid).Error; err != nil {
+		return nil, fmt.Errorf("price change not found")
+	}
+	if _, _, err := r.ownProduct(ctx, fmt.Sprint(row.ProductID)); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := r.Prices.Cancel(ctx, row.ID)
+	if err != nil {
+		return nil, err
+	}
+	return mapPriceChangeToGQL(cancelled), nil
+}
+
+// PriceHistory lists applied and scheduled prices for a product
+func (r *productResolver) PriceHistory(ctx context.Context, obj *gql.Product) ([]*gql.PriceChange, error) {
+	pid, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, errors.New("invalid product ID")
+	}
+
+	rows, err := r.Prices.History(ctx, uint(pid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price history: %v", err)
+	}
+
+	out := []*gql.PriceChange{}
+	for i := range rows {
+		out = append(out, mapPriceChangeToGQL(&rows[i]))
+	}
+	return out, nil
+}
+
+func mapPriceChangeToGQL(p *models.ProductPrice) *gql.PriceChange {
+	return &gql.PriceChange{
+		ID:          fmt.Sprint(p.ID),
+		ProductID:   fmt.Sprint(p.ProductID),
+		Price:       p.Price,
+		EffectiveAt: p.EffectiveAt,
+		Status:      gql.PriceChangeStatus(p.Status),
+		CreatedAt:   p.CreatedAt,
+	}
+}
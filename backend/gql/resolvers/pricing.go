@@ -0,0 +1,215 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/middleware"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/pricing"
+)
+
+// MyRestaurant returns the calling admin's pricing rules
+func (r *queryResolver) MyRestaurant(ctx context.Context) (*gql.Restaurant, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	rest, err := r.Pricing.Restaurant(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch restaurant: %v", err)
+	}
+	if rest == nil {
+		return nil, nil
+	}
+	return mapRestaurantToGQL(rest), nil
+}
+
+// SaveRestaurant creates or replaces the calling admin's pricing rules
+func (r *mutationResolver) SaveRestaurant(ctx context.Context, input gql.RestaurantInput) (*gql.Restaurant, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if (input.Lat == nil) != (input.Lng == nil) {
+		return nil, errors.New("lat and lng must be set together")
+	}
+	for _, m := range []models.Money{input.PackagingFee, input.DeliveryBaseFee, input.DeliveryPerKm,
+		input.SmallOrderThreshold, input.SmallOrderFee, input.PlatformFee} {
+		if m.IsNegative() {
+			return nil, errors.New("fees cannot be negative")
+		}
+	}
+	if input.DeliveryBaseKm < 0 {
+		return nil, errors.New("deliveryBaseKm cannot be negative")
+	}
+
+	rates := map[string]int64{}
+	for _, rate := range input.GstRates {
+		bps, err := percentToBps(rate.Rate)
+		if err != nil {
+			return nil, err
+		}
+		rates[rate.Category] = bps
+	}
+	ratesJSON, _ := json.Marshal(rates)
+	defaultBps, err := percentToBps(input.DefaultGstRate)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := &models.Restaurant{
+		AdminID:             uid,
+		Name:                input.Name,
+		Lat:                 input.Lat,
+		Lng:                 input.Lng,
+		GSTRates:            ratesJSON,
+		DefaultGSTBps:       defaultBps,
+		PackagingFee:        input.PackagingFee,
+		DeliveryBaseFee:     input.DeliveryBaseFee,
+		DeliveryBaseKm:      input.DeliveryBaseKm,
+		DeliveryPerKm:       input.DeliveryPerKm,
+		SmallOrderThreshold: input.SmallOrderThreshold,
+		SmallOrderFee:       input.SmallOrderFee,
+		PlatformFee:         input.PlatformFee,
+	}
+	if err := r.Pricing.SaveRestaurant(ctx, rest); err != nil {
+		return nil, fmt.Errorf("failed to save restaurant: %v", err)
+	}
+	return mapRestaurantToGQL(rest), nil
+}
+
+// SetRestaurantOpen opens or closes the admin's restaurant for new orders
+func (r *mutationResolver) SetRestaurantOpen(ctx context.Context, open bool) (*gql.Restaurant, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := r.Pricing.SetOpen(ctx, uid, open)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update restaurant: %v", err)
+	}
+	return mapRestaurantToGQL(rest), nil
+}
+
+// SetDeliveryLocation sets where the user's orders are delivered
+func (r *mutationResolver) SetDeliveryLocation(ctx context.Context, lat float64, lng float64) (bool, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return false, errors.New("unauthorized")
+	}
+	if err := r.Pricing.SetDeliveryLocation(ctx, uid, pricing.Location{Lat: lat, Lng: lng}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func percentToBps(rate float64) (int64, error) {
+	if rate < 0 || rate > 100 {
+		return 0, fmt.Errorf("rate %v must be between 0 and 100", rate)
+	}
+	return int64(math.Round(rate * 100)), nil
+}
+
+func mapRestaurantToGQL(rest *models.Restaurant) *gql.Restaurant {
+	rates := map[string]int64{}
+	if len(rest.GSTRates) > 0 {
+		_ = json.Unmarshal(rest.GSTRates, &rates)
+	}
+	categories := make([]string, 0, len(rates))
+	for c := range rates {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+
+	gstRates := []*gql.GstRate{}
+	for _, c := range categories {
+		gstRates = append(gstRates, &gql.GstRate{Category: c, Rate: float64(rates[c]) / 100})
+	}
+
+	return &gql.Restaurant{
+		ID:                  fmt.Sprint(rest.ID),
+		AdminID:             fmt.Sprint(rest.AdminID),
+		Name:                rest.Name,
+		Lat:                 rest.Lat,
+		Lng:                 rest.Lng,
+		GstRates:            gstRates,
+		DefaultGstRate:      float64(rest.DefaultGSTBps) / 100,
+		PackagingFee:        rest.PackagingFee,
+		DeliveryBaseFee:     rest.DeliveryBaseFee,
+		DeliveryBaseKm:      rest.DeliveryBaseKm,
+		DeliveryPerKm:       rest.DeliveryPerKm,
+		SmallOrderThreshold: rest.SmallOrderThreshold,
+		SmallOrderFee:       rest.SmallOrderFee,
+		PlatformFee:         rest.PlatformFee,
+		Open:                rest.IsOpen,
+	}
+}
+
+func mapBillToGQL(b pricing.Bill) *gql.CartBill {
+	items := []*gql.BillItem{}
+	for _, it := range b.Items {
+		items = append(items, &gql.BillItem{
+			ProductID:    fmt.Sprint(it.ProductID),
+			Name:         it.Name,
+			Category:     it.Category,
+			RestaurantID: fmt.Sprint(it.RestaurantID),
+			Quantity:     it.Quantity,
+			UnitPrice:    it.UnitPrice,
+			LineTotal:    it.LineTotal,
+			GstRate:      float64(it.GSTBps) / 100,
+			Gst:          it.GST,
+		})
+	}
+	restaurants := []*gql.RestaurantBill{}
+	for _, rc := range b.Restaurants {
+		restaurants = append(restaurants, &gql.RestaurantBill{
+			RestaurantID:  fmt.Sprint(rc.RestaurantID),
+			Subtotal:      rc.Subtotal,
+			Gst:           rc.GST,
+			Packaging:     rc.Packaging,
+			Delivery:      rc.Delivery,
+			DistanceKm:    rc.DistanceKm,
+			SmallOrderFee: rc.SmallOrderFee,
+		})
+	}
+	return &gql.CartBill{
+		Items:         items,
+		Restaurants:   restaurants,
+		Subtotal:      b.Subtotal,
+		Gst:           b.GST,
+		Packaging:     b.Packaging,
+		Delivery:      b.Delivery,
+		SmallOrderFee: b.SmallOrderFee,
+		PlatformFee:   b.PlatformFee,
+		Discount:      b.Discount,
+		CouponCode:    optionalString(b.CouponCode),
+		Total:         b.Total,
+	}
+}
+
+// orderBillToGQL reads the bill snapshotted at checkout; older orders have none
+func orderBillToGQL(o *models.Order) *gql.CartBill {
+	if len(o.Bill) == 0 || string(o.Bill) == "null" {
+		return nil
+	}
+	var b pricing.Bill
+	if err := json.Unmarshal(o.Bill, &b); err != nil {
+		log.Printf("order %d: failed to unmarshal bill: %v", o.ID, err)
+		return nil
+	}
+	return mapBillToGQL(b)
+}
@@ -14,16 +14,21 @@ import (
 	"swiggy-clone/backend/middleware"
 	"swiggy-clone/backend/models"
 	"swiggy-clone/backend/redis"
+	"swiggy-clone/backend/services"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CREATE
 func (r *mutationResolver) CreateProduct(
 	ctx context.Context,
 	name string,
-	price float64,
+	price models.Money,
 	stock int,
 	image *string,
 	Quantity *string,
+	category *string,
 ) (*gql.Product, error) {
 
 	userID, ok := middleware.UserIDFromCtx(ctx)
@@ -33,22 +38,49 @@ func (r *mutationResolver) CreateProduct(
 
 	// 🔍 DEBUG: Log incoming values from GraphQL mutation
 
+	if err := r.checkImageRef(ctx, image); err != nil {
+		return nil, err
+	}
+	if price.Amount <= 0 {
+		return nil, services.ErrInvalidPrice
+	}
+
 	// Create Product instance
+	// Stock starts at zero; the opening quantity is booked through the ledger below
 	p := models.Product{
 		Name:     name,
 		Price:    price,
-		Stock:    stock,
 		Quantity: Quantity,
 		AdminID:  userID,
 		Image:    image,
+		Category: "food",
+	}
+	if category != nil && *category != "" {
+		p.Category = *category
 	}
 
 	// 🔍 DEBUG: Log mapped struct before saving
 	fmt.Println("🛠️ [CreateProduct] Saving to DB:")
 	fmt.Printf("%+v\n", p)
 
-	// Save to DB
-	if err := r.DB.Create(&p).Error; err != nil {
+	// Save to DB, with its first price and opening stock, all or nothing
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&p).Error; err != nil {
+			return err
+		}
+		if err := r.Prices.Record(tx, p.ID, p.Price, userID); err != nil {
+			return err
+		}
+		if stock > 0 {
+			stocked, err := r.Inventory.RestockTx(tx, userID, p.ID, stock, "initial stock")
+			if err != nil {
+				return err
+			}
+			p = *stocked
+		}
+		return nil
+	})
+	if err != nil {
 		fmt.Println("❌ [CreateProduct] Error saving to DB:", err)
 		return nil, err
 	}
@@ -63,7 +95,7 @@ func (r *mutationResolver) CreateProduct(
 }
 
 // UPDATE
-func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, name *string, price *float64, stock *int, image *string, Quantity *string) (*gql.Product, error) {
+func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, name *string, price *models.Money, stock *int, image *string, Quantity *string, category *string) (*gql.Product, error) {
 	var p models.Product
 	fmt.Println("🚀 [CreateProduct] Received input:")
 	fmt.Println("📝 Name:", name)
@@ -75,23 +107,52 @@ func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, name *s
 	if err := r.DB.First(&p, id).Error; err != nil {
 		return nil, err
 	}
-	if name != nil {
-		p.Name = *name
-	}
-	if price != nil {
-		p.Price = *price
+	if err := r.checkImageRef(ctx, image); err != nil {
+		return nil, err
 	}
-	if stock != nil {
-		p.Stock = *stock
+	uid, _ := middleware.UserIDFromCtx(ctx)
+
+	// Only the edited columns are written, so stock, availability and price,
+	// which have their own paths, are never overwritten with a stale copy.
+	// Everything below commits together.
+	updates := map[string]interface{}{}
+	if name != nil {
+		updates["name"] = *name
 	}
 	if image != nil {
-		p.Image = image
+		updates["image"] = *image
 	}
 	if Quantity != nil {
-		p.Quantity = Quantity
+		updates["quantity"] = *Quantity
+	}
+	if category != nil && *category != "" {
+		updates["category"] = *category
 	}
 
-	if err := r.DB.Save(&p).Error; err != nil {
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, p.ID).Error; err != nil {
+			return err
+		}
+		// Price changes are recorded in the price history
+		if price != nil && price.Amount != p.Price.Amount {
+			if err := r.Prices.ChangeTx(tx, uid, p.ID, *price); err != nil {
+				return err
+			}
+		}
+		// Stock changes go through the inventory ledger as a manual adjustment
+		if stock != nil && *stock != p.Stock {
+			if _, err := r.Inventory.AdjustTx(tx, uid, p.ID, *stock-p.Stock, "stock set via updateProduct"); err != nil {
+				return err
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&models.Product{}).Where("id = ?", p.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return tx.First(&p, p.ID).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	redis.DelPattern(ctx, "products:*")
@@ -203,5 +264,54 @@ func mapProductToGQL(p *models.Product) *gql.Product {
 		AdminID:   int(p.AdminID),
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
+
+		LowStockThreshold: p.LowStockThreshold,
+		Available:         p.IsAvailable && p.Stock > 0,
+		Category:          p.Category,
+	}
+}
+
+// checkImageRef rejects product images that look like uploaded asset IDs but
+// don't exist. Plain URLs are still accepted for older products.
+func (r *Resolver) checkImageRef(ctx context.Context, image *string) error {
+	if image == nil || !services.IsAssetID(*image) || r.ImageService == nil {
+		return nil
+	}
+	if !r.ImageService.Exists(ctx, *image) {
+		return fmt.Errorf("image %s not found; upload it first", *image)
+	}
+	return nil
+}
+
+// ImageURL resolves Product.image to a fetchable URL
+func (r *productResolver) ImageURL(ctx context.Context, obj *gql.Product) (*string, error) {
+	return r.productImageURL(obj, services.VariantOriginal), nil
+}
+
+// ThumbnailURL resolves a resized variant of Product.image
+func (r *productResolver) ThumbnailURL(ctx context.Context, obj *gql.Product, size *string) (*string, error) {
+	variant := "md"
+	if size != nil && *size != "" {
+		variant = *size
+	}
+	if _, ok := services.ThumbnailSizes[variant]; !ok {
+		return nil, fmt.Errorf("unknown thumbnail size %q", variant)
+	}
+	return r.productImageURL(obj, variant), nil
+}
+
+func (r *productResolver) productImageURL(obj *gql.Product, variant string) *string {
+	if obj.Image == nil || *obj.Image == "" {
+		return nil
+	}
+	// Legacy products store a URL directly; there are no thumbnails for those
+	if !services.IsAssetID(*obj.Image) || r.ImageService == nil {
+		return obj.Image
 	}
+	return strPtr(r.ImageService.URL(*obj.Image, variant))
 }
+
+// Product returns gql.ProductResolver implementation.
+func (r *Resolver) Product() gql.ProductResolver { return &productResolver{r} }
+
+type productResolver struct{ *Resolver }
@@ -5,6 +5,9 @@ package resolvers
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 import (
+	"swiggy-clone/backend/events"
+	"swiggy-clone/backend/idempotency"
+	"swiggy-clone/backend/kafka"
 	"swiggy-clone/backend/services"
 
 	"gorm.io/gorm"
@@ -15,4 +18,18 @@ type Resolver struct {
 	DB              *gorm.DB
 	JWTSecret       string
 	CheckoutService *services.CheckoutService
+	ImageService    *services.ImageService
+	Inventory       *services.InventoryService
+	Notifications   *services.NotificationService
+	Prices          *services.PriceService
+	Pricing         *services.PricingService
+	Coupons         *services.CouponService
+	Carts           *services.CartService
+	Favorites       *services.FavoritesService
+	Orders          *services.OrderService
+	OrderFeed       *services.OrderFeed
+	OrderJobs       *kafka.PostgresQueue
+	Events          *events.Bus
+	Idempotency     *idempotency.Store
+	MaxCartQuantity int
 }
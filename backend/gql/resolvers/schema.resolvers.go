@@ -8,12 +8,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"swiggy-clone/backend/events"
 	"swiggy-clone/backend/gql"
 	"swiggy-clone/backend/models"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // Signup is the resolver for the signup field.
@@ -45,9 +47,22 @@ func (r *mutationResolver) Signup(ctx context.Context, input gql.SignupInput) (*
 	if picture != nil {
 		user.Picture = picture
 	}
-	if err := r.DB.Create(&user).Error; err != nil {
+	if err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return r.Events.Publish(tx, events.UserRegistered{
+			UserID: user.ID,
+			Email:  user.Email,
+			Name:   user.Name,
+			Role:   user.Role,
+		})
+	}); err != nil {
 		return nil, fmt.Errorf("failed to create user: %v", err)
 	}
+	r.Events.Wake()
+
+	cartConflict := r.mergeGuestCart(ctx, user.ID)
 
 	// Generate JWT (✅ clean RegisteredClaims only)
 	claims := jwt.RegisteredClaims{
@@ -72,6 +87,7 @@ func (r *mutationResolver) Signup(ctx context.Context, input gql.SignupInput) (*
 			Picture:   user.Picture,
 			CreatedAt: user.CreatedAt,
 		},
+		CartConflict: cartConflict,
 	}, nil
 }
 
@@ -87,6 +103,8 @@ func (r *mutationResolver) Login(ctx context.Context, email string, password str
 		return nil, fmt.Errorf("invalid email or password")
 	}
 
+	cartConflict := r.mergeGuestCart(ctx, user.ID)
+
 	// Generate JWT (✅ clean RegisteredClaims only)
 	claims := jwt.RegisteredClaims{
 		Subject:   fmt.Sprint(user.ID), // ensure string
@@ -109,6 +127,7 @@ func (r *mutationResolver) Login(ctx context.Context, email string, password str
 			Role:      user.Role,
 			CreatedAt: user.CreatedAt,
 		},
+		CartConflict: cartConflict,
 	}, nil
 }
 
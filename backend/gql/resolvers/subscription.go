@@ -0,0 +1,89 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"swiggy-clone/backend/gql"
+	"swiggy-clone/backend/middleware"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+)
+
+// OrderStatusChanged streams an order each time its status changes, to its
+// customer or to an admin whose products are in it
+func (r *subscriptionResolver) OrderStatusChanged(ctx context.Context, orderID string) (<-chan *gql.Order, error) {
+	uid, ok := middleware.UserIDFromCtx(ctx)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	oid, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID")
+	}
+	var order models.Order
+	if err := r.DB.WithContext(ctx).
+		Where("id = ? AND (user_id = ? OR ? = ANY(product_admins))", oid, uid, fmt.Sprint(uid)).
+		First(&order).Error; err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+
+	events := r.OrderFeed.Subscribe(ctx, func(ev redis.OrderEvent) bool {
+		return ev.OrderID == order.ID
+	})
+	return r.streamOrders(ctx, events, true), nil
+}
+
+// NewOrders streams orders as they are placed with the admin's products
+func (r *subscriptionResolver) NewOrders(ctx context.Context) (<-chan *gql.Order, error) {
+	uid, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	admin := fmt.Sprint(uid)
+
+	events := r.OrderFeed.Subscribe(ctx, func(ev redis.OrderEvent) bool {
+		if ev.Status != models.OrderPlaced {
+			return false
+		}
+		for _, a := range ev.Admins {
+			if a == admin {
+				return true
+			}
+		}
+		return false
+	})
+	return r.streamOrders(ctx, events, false), nil
+}
+
+// streamOrders loads the current order for each event. With endOnTerminal the
+// stream closes once the order can't change any more.
+func (r *Resolver) streamOrders(ctx context.Context, events <-chan redis.OrderEvent, endOnTerminal bool) <-chan *gql.Order {
+	out := make(chan *gql.Order, 1)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			var order models.Order
+			if err := r.DB.WithContext(ctx).Preload("Items").First(&order, ev.OrderID).Error; err != nil {
+				log.Printf("subscription: failed to load order %d: %v", ev.OrderID, err)
+				continue
+			}
+			select {
+			case out <- mapOrderToGQL(&order):
+			case <-ctx.Done():
+				return
+			}
+			if endOnTerminal && order.Status.Terminal() {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (r *Resolver) Subscription() gql.SubscriptionResolver { return &subscriptionResolver{r} }
+
+type subscriptionResolver struct{ *Resolver }
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"swiggy-clone/backend/middleware"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/services"
+	"swiggy-clone/backend/storage"
+)
+
+// ImageHandler exposes image upload and download over plain HTTP
+// (multipart uploads don't fit well through GraphQL).
+type ImageHandler struct {
+	DB     *gorm.DB
+	Images *services.ImageService
+}
+
+type uploadResponse struct {
+	ID         string            `json:"id"`
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// Upload handles POST /images with a multipart "file" field. Admins only.
+func (h *ImageHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.UserIDFromCtx(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var user models.User
+	if err := h.DB.First(&user, uid).Error; err != nil || user.Role != "admin" {
+		http.Error(w, "only admins can upload images", http.StatusForbidden)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, services.ErrImageTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "missing file field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	asset, err := h.Images.Upload(r.Context(), uid, file)
+	switch {
+	case errors.Is(err, services.ErrImageTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, services.ErrUnsupportedImage), errors.Is(err, services.ErrImageDimensionLimit):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	case err != nil:
+		log.Printf("image upload failed: %v", err)
+		http.Error(w, "upload failed", http.StatusInternalServerError)
+		return
+	}
+
+	resp := uploadResponse{
+		ID:         asset.ID,
+		URL:        h.Images.URL(asset.ID, services.VariantOriginal),
+		Thumbnails: map[string]string{},
+	}
+	for variant := range services.ThumbnailSizes {
+		resp.Thumbnails[variant] = h.Images.URL(asset.ID, variant)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Serve handles GET /images/{id}?variant=sm|md. Assets never change once
+// uploaded, so they are cached aggressively.
+func (h *ImageHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	variant := r.URL.Query().Get("variant")
+	if variant == "" {
+		variant = services.VariantOriginal
+	}
+
+	etag := `"` + id + "-" + variant + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rc, contentType, err := h.Images.Open(r.Context(), id, variant)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, services.ErrUnknownImageVariant):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("image fetch failed for %s/%s: %v", id, variant, err)
+		http.Error(w, "failed to load image", http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	io.Copy(w, rc)
+}
+
+// LimitBody caps the request body size before anything (including the JWT
+// middleware, which buffers the body) reads it.
+func LimitBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"swiggy-clone/backend/middleware"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+	"swiggy-clone/backend/services"
+)
+
+const sseHeartbeat = 15 * time.Second
+
+// OrderEventsHandler streams an order's status changes as Server-Sent Events,
+// for clients that can't keep a websocket open. It listens to the same feed
+// as the GraphQL subscriptions.
+type OrderEventsHandler struct {
+	DB     *gorm.DB
+	Orders *services.OrderService
+	Feed   *services.OrderFeed
+
+	// Closed when the server starts shutting down; open streams end so they
+	// don't hold up draining. Clients reconnect elsewhere with Last-Event-ID.
+	Closing <-chan struct{}
+}
+
+// orderEvent is the data of one SSE message; its id is the history entry's
+type orderEvent struct {
+	OrderID          string     `json:"orderId"`
+	Status           string     `json:"status"`
+	PreviousStatus   string     `json:"previousStatus,omitempty"`
+	Actor            string     `json:"actor"`
+	Reason           string     `json:"reason,omitempty"`
+	At               time.Time  `json:"at"`
+	PrepMinutes      *int       `json:"prepMinutes,omitempty"`
+	EstimatedReadyAt *time.Time `json:"estimatedReadyAt,omitempty"`
+}
+
+// Stream handles GET /orders/{id}/events for the order's customer. The token
+// goes in the Authorization header or, for EventSource which can't set
+// headers, an access_token query parameter (taken off the URL before it is
+// logged, by middleware.StripAccessToken). Reconnecting with Last-Event-ID
+// replays whatever was missed from the order's status history.
+func (h *OrderEventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if token := middleware.AccessTokenFromCtx(r.Context()); auth == "" && token != "" {
+		auth = "Bearer " + token
+	}
+	ctx, err := middleware.WithBearerToken(r.Context(), auth)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	uid, _ := middleware.UserIDFromCtx(ctx)
+
+	oid, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid order ID", http.StatusBadRequest)
+		return
+	}
+	var order models.Order
+	if err := h.DB.WithContext(ctx).Where("id = ? AND user_id = ?", oid, uid).First(&order).Error; err != nil {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+
+	var lastID uint
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			lastID = uint(id)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let nginx buffer the stream
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	// Listen before replaying so nothing lands in between
+	events := h.Feed.Subscribe(ctx, func(ev redis.OrderEvent) bool { return ev.OrderID == order.ID })
+
+	// send writes every history entry after lastID; the feed only says that
+	// something changed, the history is what gets sent
+	send := func() (done bool, err error) {
+		entries, err := h.Orders.HistorySince(ctx, order.ID, lastID)
+		if err != nil || len(entries) == 0 {
+			return false, err
+		}
+		if err := h.DB.WithContext(ctx).First(&order, order.ID).Error; err != nil {
+			return false, err
+		}
+		for _, e := range entries {
+			data, _ := json.Marshal(orderEvent{
+				OrderID:          fmt.Sprint(order.ID),
+				Status:           string(e.ToStatus),
+				PreviousStatus:   string(e.FromStatus),
+				Actor:            string(e.ActorType),
+				Reason:           e.Reason,
+				At:               e.CreatedAt,
+				PrepMinutes:      order.PrepMinutes,
+				EstimatedReadyAt: order.ReadyBy,
+			})
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: status\ndata: %s\n\n", e.ID, data); err != nil {
+				return false, err
+			}
+			lastID = e.ID
+		}
+		return order.Status.Terminal(), rc.Flush()
+	}
+
+	done, err := send()
+	if err == nil && !done {
+		err = rc.Flush()
+	}
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for err == nil && !done {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.Closing:
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			done, err = send()
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(w, ": ping\n\n"); err == nil {
+				err = rc.Flush()
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("order events: stream for order %d ended: %v", order.ID, err)
+	}
+}
@@ -0,0 +1,118 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrInFlight means another request with the same key is still running
+	ErrInFlight = errors.New("a request with this idempotency key is already in progress")
+	// ErrFingerprintMismatch means the key was reused with a different payload
+	ErrFingerprintMismatch = errors.New("idempotency key was already used with a different request")
+)
+
+const (
+	statePending = "pending"
+	stateDone    = "done"
+)
+
+type record struct {
+	Fingerprint string          `json:"fp"`
+	State       string          `json:"state"`
+	Response    json.RawMessage `json:"resp,omitempty"`
+}
+
+// Store remembers the outcome of requests by (scope, user, key) so a retried
+// request gets the original response instead of doing the work twice.
+type Store struct {
+	RDB       *goredis.Client
+	LockTTL   time.Duration // how long an in-flight request holds the key
+	ResultTTL time.Duration // how long a finished response is replayed
+}
+
+func NewStore(rdb *goredis.Client, resultTTL time.Duration) *Store {
+	return &Store{RDB: rdb, LockTTL: 30 * time.Second, ResultTTL: resultTTL}
+}
+
+// Fingerprint hashes the parts of a request that must match on replay
+func Fingerprint(parts ...any) string {
+	b, _ := json.Marshal(parts)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func redisKey(scope string, userID uint, key string) string {
+	return fmt.Sprintf("idem:%s:user:%d:%s", scope, userID, key)
+}
+
+// Do runs fn once per (scope, userID, key). A repeat call with the same
+// fingerprint returns the stored response; a different fingerprint returns
+// ErrFingerprintMismatch and a call while the first is running returns
+// ErrInFlight. Failed calls release the key so the client can retry.
+func Do[T any](ctx context.Context, s *Store, scope string, userID uint, key, fingerprint string, fn func() (T, error)) (T, error) {
+	var zero T
+	rk := redisKey(scope, userID, key)
+
+	pending, _ := json.Marshal(record{Fingerprint: fingerprint, State: statePending})
+	acquired, err := s.RDB.SetNX(ctx, rk, pending, s.LockTTL).Result()
+	if err != nil {
+		return zero, fmt.Errorf("idempotency lock: %w", err)
+	}
+
+	if !acquired {
+		raw, err := s.RDB.Get(ctx, rk).Bytes()
+		if errors.Is(err, goredis.Nil) {
+			// Expired between SETNX and GET; treat as in flight and let the client retry
+			return zero, ErrInFlight
+		}
+		if err != nil {
+			return zero, fmt.Errorf("idempotency lookup: %w", err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return zero, fmt.Errorf("idempotency record: %w", err)
+		}
+		if rec.Fingerprint != fingerprint {
+			return zero, ErrFingerprintMismatch
+		}
+		if rec.State != stateDone {
+			return zero, ErrInFlight
+		}
+
+		var out T
+		if err := json.Unmarshal(rec.Response, &out); err != nil {
+			return zero, fmt.Errorf("idempotency replay: %w", err)
+		}
+		return out, nil
+	}
+
+	out, err := fn()
+	if err != nil {
+		// Nothing was done (or it was rolled back): free the key for a retry
+		if delErr := s.RDB.Del(context.WithoutCancel(ctx), rk).Err(); delErr != nil {
+			log.Printf("idempotency: failed to release %s: %v", rk, delErr)
+		}
+		return zero, err
+	}
+
+	resp, err := json.Marshal(out)
+	if err == nil {
+		done, _ := json.Marshal(record{Fingerprint: fingerprint, State: stateDone, Response: resp})
+		err = s.RDB.Set(context.WithoutCancel(ctx), rk, done, s.ResultTTL).Err()
+	}
+	if err != nil {
+		// The work is done; a failed save only means a retry can't be replayed from Redis
+		log.Printf("idempotency: failed to store response for %s: %v", rk, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnprocessable marks an event that can never be handled, such as one of
+// an unknown type. Durable queues dead-letter it straight away instead of
+// retrying.
+var ErrUnprocessable = errors.New("event cannot be processed")
+
+// Event is an encoded domain event as it travels through an OrderQueue. The
+// queues only move it around; the events package knows what Payload means.
+//
+// ID is the deduplication ID: a redelivered event keeps it, so consumers can
+// tell a repeat from a new event.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	Key        string          `json:"key"`               // events with the same key are delivered in order
+	OrderID    uint            `json:"orderId,omitempty"` // the order the event is about, if any
+	OccurredAt time.Time       `json:"occurredAt"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Encode serialises the event for a transport
+func (e Event) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// DecodeEvent reads an event written by Encode
+func DecodeEvent(data []byte) (Event, error) {
+	var ev Event
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return Event{}, fmt.Errorf("%w: decode event: %v", ErrUnprocessable, err)
+	}
+	if ev.ID == "" || ev.Type == "" {
+		return Event{}, fmt.Errorf("%w: event without an ID or type", ErrUnprocessable)
+	}
+	return ev, nil
+}
+
+// StoredEvent reads the event kept in a queue or outbox row. Rows written
+// before events carried a payload have only their columns to go on; those
+// were all order events, whose version 1 payload held just the order ID.
+func StoredEvent(payload []byte, id, typ string, orderID uint, at time.Time) (Event, error) {
+	if len(payload) > 0 {
+		return DecodeEvent(payload)
+	}
+	if id == "" || typ == "" {
+		return Event{}, fmt.Errorf("%w: stored event without an ID or type", ErrUnprocessable)
+	}
+	data, err := json.Marshal(struct {
+		OrderID uint `json:"orderId"`
+	}{orderID})
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:         id,
+		Type:       typ,
+		Version:    1,
+		Key:        fmt.Sprintf("order:%d", orderID), // same key the events package gives order events
+		OrderID:    orderID,
+		OccurredAt: at,
+		Payload:    data,
+	}, nil
+}
@@ -0,0 +1,12 @@
+package kafka
+
+// MessageReader lets tests supply their own consumer group member
+type MessageReader = messageReader
+
+// NewKafkaQueueWith returns a queue that writes and reads through the given
+// stand-ins for kafka-go instead of a broker
+func NewKafkaQueueWith(topic string, writer, dlqWriter messageWriter, newReader func() messageReader) *KafkaQueue {
+	q := NewKafkaQueue(nil, topic, "test")
+	q.writer, q.dlqWriter, q.newReader = writer, dlqWriter, newReader
+	return q
+}
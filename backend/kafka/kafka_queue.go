@@ -0,0 +1,207 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// KafkaQueue publishes events to a Kafka (or Redpanda) topic and consumes
+// them with a consumer group. Messages are keyed by the event's key (the order
+// ID for order events), so every event for an order lands on the same
+// partition and is handled in order.
+//
+// A message whose handler keeps failing is retried in place with backoff,
+// then copied to the "<topic>.dlq" topic and committed so the partition can
+// move on.
+type KafkaQueue struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+
+	MaxAttempts   int
+	BaseBackoff   time.Duration // wait before the first retry, doubled after each
+	MaxBackoff    time.Duration
+	HandleTimeout time.Duration
+
+	writer    messageWriter
+	dlqWriter messageWriter
+	newReader func() messageReader // joins the consumer group
+}
+
+// messageWriter and messageReader are the parts of kafka-go's Writer and
+// Reader the queue uses
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafkago.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+// NewKafkaQueue returns a queue for the topic with sensible retry defaults
+func NewKafkaQueue(brokers []string, topic, groupID string) *KafkaQueue {
+	q := &KafkaQueue{
+		Brokers:       brokers,
+		Topic:         topic,
+		GroupID:       groupID,
+		MaxAttempts:   8,
+		BaseBackoff:   time.Second,
+		MaxBackoff:    time.Minute,
+		HandleTimeout: time.Minute,
+		writer:        newWriter(brokers, topic),
+		dlqWriter:     newWriter(brokers, topic+".dlq"),
+	}
+	q.newReader = func() messageReader {
+		return kafkago.NewReader(kafkago.ReaderConfig{
+			Brokers:  q.Brokers,
+			Topic:    q.Topic,
+			GroupID:  q.GroupID,
+			MinBytes: 1,
+			MaxBytes: 10 << 20,
+		})
+	}
+	return q
+}
+
+func newWriter(brokers []string, topic string) *kafkago.Writer {
+	return &kafkago.Writer{
+		Addr:                   kafkago.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafkago.Hash{}, // same key, same partition
+		RequiredAcks:           kafkago.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+}
+
+// Publish writes the event under its key and waits for the brokers to accept it
+func (q *KafkaQueue) Publish(ctx context.Context, ev Event) error {
+	value, err := ev.Encode()
+	if err != nil {
+		return err
+	}
+	key := ev.Key
+	if key == "" {
+		key = ev.ID
+	}
+	err = q.writer.WriteMessages(ctx, kafkago.Message{
+		Key:   []byte(key),
+		Value: value,
+		Headers: []kafkago.Header{
+			{Key: "event-id", Value: []byte(ev.ID)},
+			{Key: "event-type", Value: []byte(ev.Type)},
+			{Key: "event-version", Value: []byte(strconv.Itoa(ev.Version))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("publish %s %s: %w", ev.Type, ev.ID, err)
+	}
+	return nil
+}
+
+// Work joins the consumer group as its own member and handles messages until
+// ctx is done. Each worker gets its own partitions, so running several keeps
+// events for one order in order.
+func (q *KafkaQueue) Work(ctx context.Context, handle OrderHandler) {
+	reader := q.newReader()
+	defer reader.Close()
+	log.Printf("🛠️ Order worker joined group %q on topic %q...", q.GroupID, q.Topic)
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("kafka %s: fetch failed: %v", q.Topic, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(q.BaseBackoff):
+			}
+			continue
+		}
+		if err := q.process(ctx, msg, handle); err != nil {
+			// Only shutdown stops processing; the message is redelivered
+			// to whoever owns the partition next
+			return
+		}
+		// Committed even when shutdown has begun, so a handled message isn't
+		// handled again
+		commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		if err := reader.CommitMessages(commitCtx, msg); err != nil {
+			log.Printf("kafka %s: commit of offset %d failed: %v", q.Topic, msg.Offset, err)
+		}
+		cancel()
+	}
+}
+
+// process handles one message, retrying failures, and sends it to the DLQ
+// once it is out of attempts. It only returns an error if ctx ends first.
+func (q *KafkaQueue) process(ctx context.Context, msg kafkago.Message, handle OrderHandler) error {
+	ev, err := DecodeEvent(msg.Value)
+	if err != nil {
+		// Retrying won't make it readable
+		return q.deadLetter(ctx, msg, 0, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.HandleTimeout)
+		err = safeHandle(runCtx, handle, ev)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		log.Printf("kafka %s: %s %s failed (attempt %d/%d): %v", q.Topic, ev.Type, ev.ID, attempt, q.MaxAttempts, err)
+		if attempt >= q.MaxAttempts || errors.Is(err, ErrUnprocessable) {
+			return q.deadLetter(ctx, msg, attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(q.backoff(attempt)):
+		}
+	}
+}
+
+// deadLetter copies the message to the DLQ topic with why it failed
+func (q *KafkaQueue) deadLetter(ctx context.Context, msg kafkago.Message, attempts int, cause error) error {
+	headers := append(msg.Headers,
+		kafkago.Header{Key: "dlq-error", Value: []byte(cause.Error())},
+		kafkago.Header{Key: "dlq-attempts", Value: []byte(strconv.Itoa(attempts))},
+		kafkago.Header{Key: "dlq-source", Value: []byte(fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset))},
+	)
+	for {
+		err := q.dlqWriter.WriteMessages(ctx, kafkago.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+		if err == nil {
+			log.Printf("kafka %s: offset %d moved to %s.dlq", q.Topic, msg.Offset, q.Topic)
+			return nil
+		}
+		log.Printf("kafka %s: dead-letter write failed: %v", q.Topic, err)
+		// Committing without a DLQ copy would lose the message, so keep trying
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(q.MaxBackoff):
+		}
+	}
+}
+
+func (q *KafkaQueue) backoff(attempt int) time.Duration {
+	d := q.BaseBackoff
+	for i := 1; i < attempt && d < q.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.MaxBackoff)
+}
+
+// Close flushes and closes the producers
+func (q *KafkaQueue) Close() error {
+	return errors.Join(q.writer.Close(), q.dlqWriter.Close())
+}
@@ -0,0 +1,447 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"swiggy-clone/backend/events"
+	"swiggy-clone/backend/kafka"
+)
+
+// fakeBroker is an in-process stand-in for a Kafka cluster: topics split
+// into partitions, the same key-hash partitioner the real writer uses, and a
+// consumer group that shares partitions between its members.
+type fakeBroker struct {
+	partitions int
+
+	mu        sync.Mutex
+	topics    map[string][][]kafkago.Message
+	committed map[string]map[int]int64 // next offset to read, per partition
+	members   int
+	changed   chan struct{} // closed on every write
+}
+
+func newFakeBroker(partitions int) *fakeBroker {
+	return &fakeBroker{
+		partitions: partitions,
+		topics:     map[string][][]kafkago.Message{},
+		committed:  map[string]map[int]int64{},
+		changed:    make(chan struct{}),
+	}
+}
+
+func (b *fakeBroker) topic(name string) [][]kafkago.Message {
+	if _, ok := b.topics[name]; !ok {
+		b.topics[name] = make([][]kafkago.Message, b.partitions)
+	}
+	return b.topics[name]
+}
+
+// messages returns everything written to the topic, partition by partition
+func (b *fakeBroker) messages(topic string) []kafkago.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []kafkago.Message
+	for _, p := range b.topic(topic) {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func (b *fakeBroker) committedCount(topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var n int64
+	for _, offset := range b.committed[topic] {
+		n += offset
+	}
+	return n
+}
+
+type fakeWriter struct {
+	b     *fakeBroker
+	topic string
+}
+
+func (b *fakeBroker) writer(topic string) *fakeWriter {
+	return &fakeWriter{b: b, topic: topic}
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafkago.Message) error {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	parts := w.b.topic(w.topic)
+	ids := make([]int, len(parts))
+	for i := range ids {
+		ids[i] = i
+	}
+	for _, m := range msgs {
+		p := (&kafkago.Hash{}).Balance(m, ids...)
+		m.Topic, m.Partition, m.Offset = w.topic, p, int64(len(parts[p]))
+		parts[p] = append(parts[p], m)
+	}
+	close(w.b.changed)
+	w.b.changed = make(chan struct{})
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+// fakeReader is one member of the consumer group. With n members, member k
+// owns the partitions p where p % n == k.
+type fakeReader struct {
+	b          *fakeBroker
+	topic      string
+	partitions []int
+	pos        map[int]int64
+	next       int
+}
+
+func (b *fakeBroker) reader(topic string, members int) func() kafka.MessageReader {
+	return func() kafka.MessageReader {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		r := &fakeReader{b: b, topic: topic, pos: map[int]int64{}}
+		for p := b.members % members; p < b.partitions; p += members {
+			r.partitions = append(r.partitions, p)
+		}
+		b.members++
+		return r
+	}
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafkago.Message, error) {
+	for {
+		r.b.mu.Lock()
+		parts := r.b.topic(r.topic)
+		for i := range r.partitions {
+			p := r.partitions[(r.next+i)%len(r.partitions)]
+			if r.pos[p] < int64(len(parts[p])) {
+				m := parts[p][r.pos[p]]
+				r.pos[p]++
+				r.next = (r.next + i + 1) % len(r.partitions)
+				r.b.mu.Unlock()
+				return m, nil
+			}
+		}
+		changed := r.b.changed
+		r.b.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return kafkago.Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafkago.Message) error {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	if r.b.committed[r.topic] == nil {
+		r.b.committed[r.topic] = map[int]int64{}
+	}
+	for _, m := range msgs {
+		r.b.committed[r.topic][m.Partition] = m.Offset + 1
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+func newTestQueue(b *fakeBroker, members int) *kafka.KafkaQueue {
+	q := kafka.NewKafkaQueueWith("orders", b.writer("orders"), b.writer("orders.dlq"), b.reader("orders", members))
+	q.MaxAttempts = 3
+	q.BaseBackoff = time.Millisecond
+	q.MaxBackoff = 5 * time.Millisecond
+	q.HandleTimeout = time.Second
+	return q
+}
+
+// runWorkers starts n workers on q; the returned func stops them and waits
+func runWorkers(q *kafka.KafkaQueue, n int, handle kafka.OrderHandler) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Work(ctx, handle)
+		}()
+	}
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func header(m kafkago.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestKafkaQueueRoundTrip(t *testing.T) {
+	b := newFakeBroker(3)
+	q := newTestQueue(b, 1)
+
+	sent := kafka.Event{
+		ID:         "ev-1",
+		Type:       events.TypeOrderPlaced,
+		Version:    2,
+		Key:        "order:7",
+		OrderID:    7,
+		OccurredAt: time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC),
+		Payload:    json.RawMessage(`{"orderId":7,"userId":3,"admins":["5"],"total":24950}`),
+	}
+	if err := q.Publish(context.Background(), sent); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	got := make(chan kafka.Event, 1)
+	stop := runWorkers(q, 1, func(_ context.Context, ev kafka.Event) error {
+		got <- ev
+		return nil
+	})
+	var received kafka.Event
+	select {
+	case received = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+	waitFor(t, "the offset to be committed", func() bool { return b.committedCount("orders") == 1 })
+	stop()
+
+	if !reflect.DeepEqual(received, sent) {
+		t.Errorf("received %+v, want %+v", received, sent)
+	}
+	msg := b.messages("orders")[0]
+	if string(msg.Key) != sent.Key {
+		t.Errorf("message key = %q, want %q", msg.Key, sent.Key)
+	}
+	if header(msg, "event-version") != "2" || header(msg, "event-type") != sent.Type {
+		t.Errorf("headers = %v", msg.Headers)
+	}
+}
+
+func TestKafkaQueueKeepsOrderPerKey(t *testing.T) {
+	const keys, perKey = 6, 25
+	b := newFakeBroker(4)
+	q := newTestQueue(b, 2)
+
+	for i := range perKey {
+		for k := range keys {
+			ev := kafka.Event{
+				ID:      fmt.Sprintf("order-%d-%d", k, i),
+				Type:    events.TypeOrderStatusChanged,
+				Version: 1,
+				Key:     fmt.Sprintf("order:%d", k),
+				OrderID: uint(k),
+				Payload: json.RawMessage(fmt.Sprintf(`{"seq":%d}`, i)),
+			}
+			if err := q.Publish(context.Background(), ev); err != nil {
+				t.Fatalf("publish: %v", err)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	seen := map[string][]int{}
+	total := 0
+	stop := runWorkers(q, 2, func(_ context.Context, ev kafka.Event) error {
+		var p struct{ Seq int }
+		if err := json.Unmarshal(ev.Payload, &p); err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(rand.IntN(200)) * time.Microsecond)
+		mu.Lock()
+		seen[ev.Key] = append(seen[ev.Key], p.Seq)
+		total++
+		mu.Unlock()
+		return nil
+	})
+	waitFor(t, "every event", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return total == keys*perKey
+	})
+	stop()
+
+	for key, seqs := range seen {
+		for i, seq := range seqs {
+			if seq != i {
+				t.Fatalf("%s handled out of order: %v", key, seqs)
+			}
+		}
+	}
+	if len(seen) != keys {
+		t.Errorf("saw %d keys, want %d", len(seen), keys)
+	}
+}
+
+func TestKafkaQueueRetriesThenDeadLetters(t *testing.T) {
+	b := newFakeBroker(2)
+	q := newTestQueue(b, 1)
+
+	poison := kafka.Event{ID: "poison", Type: events.TypeOrderPlaced, Version: 2, Key: "order:1", OrderID: 1, Payload: json.RawMessage(`{}`)}
+	after := kafka.Event{ID: "after", Type: events.TypeOrderPlaced, Version: 2, Key: "order:1", OrderID: 1, Payload: json.RawMessage(`{}`)}
+	for _, ev := range []kafka.Event{poison, after} {
+		if err := q.Publish(context.Background(), ev); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	dlqBeforeNext := false
+	stop := runWorkers(q, 1, func(_ context.Context, ev kafka.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[ev.ID]++
+		if ev.ID == poison.ID {
+			return errors.New("restaurant service is down")
+		}
+		dlqBeforeNext = len(b.messages("orders.dlq")) == 1
+		return nil
+	})
+	waitFor(t, "both offsets to be committed", func() bool { return b.committedCount("orders") == 2 })
+	stop()
+
+	if attempts[poison.ID] != q.MaxAttempts {
+		t.Errorf("failing event tried %d times, want %d", attempts[poison.ID], q.MaxAttempts)
+	}
+	if attempts[after.ID] != 1 {
+		t.Errorf("next event tried %d times, want 1", attempts[after.ID])
+	}
+	if !dlqBeforeNext {
+		t.Error("the next event on the key ran before the failed one was dead-lettered")
+	}
+
+	dlq := b.messages("orders.dlq")
+	if len(dlq) != 1 {
+		t.Fatalf("dlq holds %d messages, want 1", len(dlq))
+	}
+	dead, err := kafka.DecodeEvent(dlq[0].Value)
+	if err != nil || dead.ID != poison.ID {
+		t.Fatalf("dlq message = %+v (%v), want %s", dead, err, poison.ID)
+	}
+	if got := header(dlq[0], "dlq-attempts"); got != "3" {
+		t.Errorf("dlq-attempts = %q, want 3", got)
+	}
+	if got := header(dlq[0], "dlq-error"); got != "restaurant service is down" {
+		t.Errorf("dlq-error = %q", got)
+	}
+	if string(dlq[0].Key) != poison.Key {
+		t.Errorf("dlq key = %q, want %q", dlq[0].Key, poison.Key)
+	}
+}
+
+func TestKafkaQueueRejectsUnknownVersion(t *testing.T) {
+	current, err := events.Encode(events.OrderPlaced{OrderID: 9, UserID: 2, Admins: []string{"4"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withVersion := func(v int) kafka.Event {
+		ev := current
+		ev.Version = v
+		return ev
+	}
+	withType := func(typ string) kafka.Event {
+		ev := current
+		ev.Type = typ
+		return ev
+	}
+
+	tests := []struct {
+		name         string
+		ev           kafka.Event
+		wantHandled  bool
+		wantAttempts string // dlq-attempts header; "" when not dead-lettered
+	}{
+		{"current version", current, true, ""},
+		{"newer version", withVersion(current.Version + 1), false, "1"},
+		{"version zero", withVersion(0), false, "1"},
+		{"unknown type", withType("order.teleported"), false, "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFakeBroker(1)
+			q := newTestQueue(b, 1)
+			if err := q.Publish(context.Background(), tt.ev); err != nil {
+				t.Fatalf("publish: %v", err)
+			}
+
+			bus := events.NewBus(nil, nil)
+			var mu sync.Mutex
+			handled := 0
+			events.Subscribe(bus, "test", 1, func(context.Context, events.OrderPlaced) error {
+				mu.Lock()
+				handled++
+				mu.Unlock()
+				return nil
+			})
+			stop := runWorkers(q, 1, bus.Dispatch)
+			waitFor(t, "the offset to be committed", func() bool { return b.committedCount("orders") == 1 })
+			stop()
+
+			if (handled == 1) != tt.wantHandled {
+				t.Errorf("handled %d times, want handled = %v", handled, tt.wantHandled)
+			}
+			dlq := b.messages("orders.dlq")
+			if tt.wantAttempts == "" {
+				if len(dlq) != 0 {
+					t.Errorf("dead-lettered a valid event")
+				}
+				return
+			}
+			if len(dlq) != 1 {
+				t.Fatalf("dlq holds %d messages, want 1", len(dlq))
+			}
+			if got := header(dlq[0], "dlq-attempts"); got != tt.wantAttempts {
+				t.Errorf("dlq-attempts = %q, want %q (rejected events must not be retried)", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestKafkaQueueDeadLettersUnreadableMessages(t *testing.T) {
+	b := newFakeBroker(1)
+	q := newTestQueue(b, 1)
+	if err := b.writer("orders").WriteMessages(context.Background(), kafkago.Message{Value: []byte("not an event")}); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := runWorkers(q, 1, func(context.Context, kafka.Event) error {
+		t.Error("handler ran for an unreadable message")
+		return nil
+	})
+	waitFor(t, "the offset to be committed", func() bool { return b.committedCount("orders") == 1 })
+	stop()
+
+	dlq := b.messages("orders.dlq")
+	if len(dlq) != 1 || header(dlq[0], "dlq-attempts") != "0" {
+		t.Fatalf("dlq = %+v, want the message with 0 attempts", dlq)
+	}
+}
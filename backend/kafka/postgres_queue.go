@@ -0,0 +1,279 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/models"
+)
+
+var ErrDeadJobNotFound = errors.New("failed job not found or already replayed")
+
+// PostgresQueue is a durable OrderQueue kept in the queue_jobs table. Workers
+// claim jobs with SELECT ... FOR UPDATE SKIP LOCKED, so any number of them,
+// on any number of instances, can share a queue without taking the same job.
+//
+// A claimed job is hidden for VisibilityTimeout. If the handler fails it is
+// retried with exponential backoff; after MaxAttempts it moves to dead_jobs.
+//
+// Jobs for one order run one at a time, in the order they were queued: a job
+// waits while an earlier job for its order is still in the queue, running or
+// waiting for a retry. Jobs not about an order (OrderID 0) are unordered.
+type PostgresQueue struct {
+	DB   *gorm.DB
+	Name string
+
+	MaxAttempts       int
+	VisibilityTimeout time.Duration // also the deadline for one handler run
+	BaseBackoff       time.Duration // wait before the first retry, doubled after each
+	MaxBackoff        time.Duration
+	PollInterval      time.Duration // how often an idle worker looks for jobs
+}
+
+// NewPostgresQueue returns a queue with sensible retry defaults
+func NewPostgresQueue(db *gorm.DB, name string) *PostgresQueue {
+	return &PostgresQueue{
+		DB:                db,
+		Name:              name,
+		MaxAttempts:       8,
+		VisibilityTimeout: time.Minute,
+		BaseBackoff:       5 * time.Second,
+		MaxBackoff:        30 * time.Minute,
+		PollInterval:      time.Second,
+	}
+}
+
+// Publish queues the event to run now
+func (q *PostgresQueue) Publish(ctx context.Context, ev Event) error {
+	return q.PublishTx(q.DB.WithContext(ctx), ev)
+}
+
+// PublishTx queues the event inside the caller's transaction. An event whose
+// ID is already waiting in the queue is not queued twice.
+func (q *PostgresQueue) PublishTx(tx *gorm.DB, ev Event) error {
+	if ev.ID == "" {
+		ev.ID = uuid.NewString()
+	}
+	payload, err := ev.Encode()
+	if err != nil {
+		return err
+	}
+	job := models.QueueJob{
+		Queue:       q.Name,
+		EventID:     ev.ID,
+		EventType:   ev.Type,
+		OrderID:     ev.OrderID,
+		Payload:     payload,
+		MaxAttempts: q.MaxAttempts,
+		RunAt:       time.Now(),
+	}
+	err = tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).
+		Create(&job).Error
+	if err != nil {
+		return fmt.Errorf("enqueue %s for order %d: %w", ev.Type, ev.OrderID, err)
+	}
+	return nil
+}
+
+// Work runs jobs one at a time until ctx is done
+func (q *PostgresQueue) Work(ctx context.Context, handle OrderHandler) {
+	log.Printf("🛠️ Order worker started on queue %q...", q.Name)
+	for ctx.Err() == nil {
+		ran, err := q.RunNext(ctx, handle)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("queue %s: %v", q.Name, err)
+		}
+		if ran {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(q.PollInterval):
+		}
+	}
+}
+
+// RunNext claims the next due job and runs it. It reports whether there was
+// a job to run. Once claimed, the job runs to completion (or to the
+// visibility timeout) even if ctx is cancelled.
+func (q *PostgresQueue) RunNext(ctx context.Context, handle OrderHandler) (bool, error) {
+	job, err := q.claim(ctx)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	// Jobs queued before events had IDs get one that stays the same on retries
+	id := job.EventID
+	if id == "" {
+		id = fmt.Sprintf("%s:%d", q.Name, job.ID)
+	}
+	ev, herr := StoredEvent(job.Payload, id, job.EventType, job.OrderID, job.CreatedAt)
+	if herr == nil {
+		runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.VisibilityTimeout)
+		herr = safeHandle(runCtx, handle, ev)
+		cancel()
+	}
+
+	// Settled on a context shutdown can't cancel, so the outcome is recorded
+	settleCtx := context.WithoutCancel(ctx)
+	if herr == nil {
+		return true, q.complete(settleCtx, job)
+	}
+	return true, q.fail(settleCtx, job, herr)
+}
+
+// claim takes the oldest due job that isn't waiting on an earlier job for its
+// order and hides it for the visibility timeout. Attempts goes up with every
+// claim, which also makes it the claim's token.
+func (q *PostgresQueue) claim(ctx context.Context) (*models.QueueJob, error) {
+	var job models.QueueJob
+	err := q.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("queue = ? AND run_at <= ?", q.Name, time.Now()).
+			Where(`order_id = 0 OR NOT EXISTS (
+				SELECT 1 FROM queue_jobs earlier
+				WHERE earlier.queue = queue_jobs.queue AND earlier.order_id = queue_jobs.order_id
+					AND earlier.id < queue_jobs.id)`).
+			Order("run_at, id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+		job.Attempts++
+		job.RunAt = time.Now().Add(q.VisibilityTimeout)
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"attempts": job.Attempts,
+			"run_at":   job.RunAt,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim job: %w", err)
+	}
+	return &job, nil
+}
+
+// complete deletes a finished job, unless its claim expired and another
+// worker has taken it since
+func (q *PostgresQueue) complete(ctx context.Context, job *models.QueueJob) error {
+	return q.DB.WithContext(ctx).
+		Where("id = ? AND attempts = ?", job.ID, job.Attempts).
+		Delete(&models.QueueJob{}).Error
+}
+
+// fail schedules a retry, or moves the job to dead_jobs once it is out of attempts
+func (q *PostgresQueue) fail(ctx context.Context, job *models.QueueJob, cause error) error {
+	msg := cause.Error()
+	log.Printf("queue %s: %s %s failed (attempt %d/%d): %s", q.Name, job.EventType, job.EventID, job.Attempts, job.MaxAttempts, msg)
+
+	if job.Attempts < job.MaxAttempts && !errors.Is(cause, ErrUnprocessable) {
+		return q.DB.WithContext(ctx).Model(&models.QueueJob{}).
+			Where("id = ? AND attempts = ?", job.ID, job.Attempts).
+			Updates(map[string]interface{}{
+				"run_at":     time.Now().Add(q.backoff(job.Attempts)),
+				"last_error": msg,
+			}).Error
+	}
+
+	return q.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND attempts = ?", job.ID, job.Attempts).Delete(&models.QueueJob{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		log.Printf("queue %s: %s %s moved to dead jobs", q.Name, job.EventType, job.EventID)
+		return tx.Create(&models.DeadJob{
+			Queue:      job.Queue,
+			EventID:    job.EventID,
+			EventType:  job.EventType,
+			OrderID:    job.OrderID,
+			Payload:    job.Payload,
+			Attempts:   job.Attempts,
+			LastError:  msg,
+			EnqueuedAt: job.CreatedAt,
+			FailedAt:   time.Now(),
+		}).Error
+	})
+}
+
+// backoff is how long to wait after the given failed attempt
+func (q *PostgresQueue) backoff(attempt int) time.Duration {
+	d := q.BaseBackoff
+	for i := 1; i < attempt && d < q.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.MaxBackoff)
+}
+
+// DeadJobs lists failed jobs for orders with the admin's products, newest
+// first. Replayed jobs are left out unless includeReplayed is set.
+func (q *PostgresQueue) DeadJobs(ctx context.Context, adminID uint, includeReplayed bool, limit int) ([]models.DeadJob, error) {
+	db := q.DB.WithContext(ctx).
+		Joins("JOIN orders ON orders.id = dead_jobs.order_id").
+		Where("dead_jobs.queue = ? AND ? = ANY(orders.product_admins)", q.Name, fmt.Sprint(adminID))
+	if !includeReplayed {
+		db = db.Where("dead_jobs.replayed_at IS NULL")
+	}
+	var out []models.DeadJob
+	err := db.Order("dead_jobs.failed_at DESC").Limit(limit).Find(&out).Error
+	return out, err
+}
+
+// Replay queues a failed job again with a fresh set of attempts
+func (q *PostgresQueue) Replay(ctx context.Context, deadJobID, adminID uint) (*models.DeadJob, error) {
+	var dead models.DeadJob
+	err := q.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "dead_jobs"}}).
+			Joins("JOIN orders ON orders.id = dead_jobs.order_id").
+			Where("dead_jobs.id = ? AND dead_jobs.queue = ? AND dead_jobs.replayed_at IS NULL", deadJobID, q.Name).
+			Where("? = ANY(orders.product_admins)", fmt.Sprint(adminID)).
+			First(&dead).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDeadJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// Same event ID: to the handler it is a redelivery
+		id := dead.EventID
+		if id == "" {
+			id = fmt.Sprintf("%s:dead:%d", q.Name, dead.ID)
+		}
+		ev, err := StoredEvent(dead.Payload, id, dead.EventType, dead.OrderID, dead.EnqueuedAt)
+		if err != nil {
+			return err
+		}
+		if err := q.PublishTx(tx, ev); err != nil {
+			return err
+		}
+		now := time.Now()
+		dead.ReplayedAt, dead.ReplayedBy = &now, &adminID
+		return tx.Model(&dead).Updates(map[string]interface{}{
+			"replayed_at": now,
+			"replayed_by": adminID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("queue %s: admin %d replayed %s %s", q.Name, adminID, dead.EventType, dead.EventID)
+	return &dead, nil
+}
+
+// safeHandle turns a panicking handler into a failed attempt
+func safeHandle(ctx context.Context, handle OrderHandler, ev Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panicked: %v", p)
+		}
+	}()
+	return handle(ctx, ev)
+}
@@ -2,43 +2,67 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"log"
 )
 
+// ErrQueueFull is returned when the in-memory queue has no room left
+var ErrQueueFull = errors.New("order queue is full")
+
+// OrderQueue delivers order events to a worker. Delivery is at least once:
+// an event may arrive again with the same ID.
 type OrderQueue interface {
-	Publish(ctx context.Context, orderID uint) error
+	Publish(ctx context.Context, ev Event) error
+}
+
+// Queue is an OrderQueue that can also be consumed. Work runs one worker
+// until ctx is done and returns once the event in hand is finished; run it in
+// several goroutines for a pool.
+type Queue interface {
+	OrderQueue
+	Work(ctx context.Context, handle OrderHandler)
 }
 
+// OrderHandler processes one event from a queue. Durable queues retry the
+// event when it returns an error.
+type OrderHandler func(ctx context.Context, ev Event) error
+
 type InMemoryQueue struct {
-	ch chan uint
+	ch chan Event
 }
 
 func NewInMemoryQueue(size int) *InMemoryQueue {
 	return &InMemoryQueue{
-		ch: make(chan uint, size),
+		ch: make(chan Event, size),
 	}
 }
 
-func (q *InMemoryQueue) Publish(ctx context.Context, orderID uint) error {
+// Publish hands the order to the worker. Nothing survives a restart, and a
+// full queue is reported to the caller rather than dropped quietly.
+func (q *InMemoryQueue) Publish(ctx context.Context, ev Event) error {
 	select {
-	case q.ch <- orderID:
+	case q.ch <- ev:
 		return nil
 	default:
-		log.Println("⚠️ Queue is full, dropping order:", orderID)
-		return nil
+		return ErrQueueFull
 	}
 }
 
-func (q *InMemoryQueue) StartWorker(ctx context.Context, handle func(context.Context, uint)) {
-	go func() {
-		log.Println("🛠️ Order worker started...")
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case orderID := <-q.ch:
-				handle(ctx, orderID)
+// Work handles events until ctx is done. Events still in the channel at
+// shutdown are lost, like everything else in this queue. Unlike the durable
+// queues it keeps no per-order ordering across workers, so it is for
+// development with a single worker.
+func (q *InMemoryQueue) Work(ctx context.Context, handle OrderHandler) {
+	log.Println("🛠️ Order worker started...")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-q.ch:
+			// Shutdown lets the event in hand finish
+			if err := safeHandle(context.WithoutCancel(ctx), handle, ev); err != nil {
+				log.Printf("⚠️ %s for order %d failed: %v", ev.Type, ev.OrderID, err)
 			}
 		}
-	}()
+	}
 }
@@ -5,87 +5,256 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors" // ✅ ADD THIS LINE
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
 
+	"swiggy-clone/backend/app"
 	"swiggy-clone/backend/config"
 	"swiggy-clone/backend/db"
+	"swiggy-clone/backend/events"
 	"swiggy-clone/backend/gql"
 	"swiggy-clone/backend/gql/resolvers"
+	"swiggy-clone/backend/handlers"
+	"swiggy-clone/backend/idempotency"
 	"swiggy-clone/backend/kafka"
 	custommiddleware "swiggy-clone/backend/middleware"
-	"swiggy-clone/backend/models"
 	"swiggy-clone/backend/redis"
 	"swiggy-clone/backend/services"
+	"swiggy-clone/backend/storage"
 )
 
+var allowedOrigins = []string{"http://localhost:3000", "https://swiggy-frontend1.vercel.app"}
+
 func main() {
 	cfg := config.Load()
+	custommiddleware.SetGuestSecret(cfg.GuestSessionSecret)
 	gdb := db.Open(cfg.DatabaseURL)
 	db.AutoMigrate(gdb)
 	redis.InitRedis(cfg.RedisURL)
 
+	// Lifecycle: background work runs on lifecycle.Context(), which SIGTERM
+	// cancels; the pools registered here close last, once everything drained
+	lifecycle := app.New(cfg.ShutdownTimeout, cfg.ReadinessDelay)
+	ctx := lifecycle.Context()
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		log.Fatalf("db: %v", err)
+	}
+	lifecycle.OnShutdown("postgres", func(context.Context) error { return sqlDB.Close() })
+	lifecycle.OnShutdown("redis", func(context.Context) error { return redis.RDB.Close() })
+	lifecycle.AddCheck("postgres", sqlDB.PingContext)
+	lifecycle.AddCheck("redis", func(ctx context.Context) error { return redis.RDB.Ping(ctx).Err() })
+
 	// ✅ Step 1: Create queue
-	queue := kafka.NewInMemoryQueue(100)
-
-	// ✅ Step 2: Start worker
-	ctx := context.Background()
-	queue.StartWorker(ctx, func(ctx context.Context, orderID uint) {
-		log.Printf("⏳ Processing order %d...\n", orderID)
-		time.Sleep(3 * time.Second)
-
-		// Update status to SUCCESS
-		if err := gdb.Model(&models.Order{}).
-			Where("id = ?", orderID).
-			Update("status", models.OrderSuccess).Error; err != nil {
-			log.Printf(" Failed to update order %d: %v", orderID, err)
-			return
-		}
+	// By default orders queue in Postgres so nothing is lost on a restart;
+	// failed jobs are retried with backoff and end up in dead_jobs for an
+	// admin to replay. Kafka keeps its failures in the "<topic>.dlq" topic.
+	var queue kafka.Queue
+	var orderJobs *kafka.PostgresQueue
+	switch cfg.QueueDriver {
+	case "kafka":
+		kq := kafka.NewKafkaQueue(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaGroupID)
+		kq.MaxAttempts = cfg.QueueMaxAttempts
+		kq.HandleTimeout = cfg.QueueVisibilityTimeout
+		lifecycle.OnShutdown("kafka producer", func(context.Context) error { return kq.Close() })
+		queue = kq
+	case "memory":
+		queue = kafka.NewInMemoryQueue(100)
+	case "postgres":
+		orderJobs = kafka.NewPostgresQueue(gdb, "orders")
+		orderJobs.MaxAttempts = cfg.QueueMaxAttempts
+		orderJobs.VisibilityTimeout = cfg.QueueVisibilityTimeout
+		queue = orderJobs
+	default:
+		log.Fatalf("unknown QUEUE_DRIVER %q", cfg.QueueDriver)
+	}
+
+	// Order lifecycle: every status change is validated and audited here
+	orderService := &services.OrderService{
+		DB:           gdb,
+		Refunds:      services.RefundPolicy{PreparingRefundBps: int64(cfg.CancelRefundPercent) * 100},
+		AcceptWindow: cfg.OrderAcceptWindow,
+	}
+	notifications := &services.NotificationService{DB: gdb}
 
-		log.Printf(" Order %d marked as SUCCESS", orderID)
+	// ✅ Step 2: Start workers
+	// Order, payment, stock and signup events are written to the outbox with
+	// the change that caused them, relayed to the queue, and fanned out to
+	// subscribers by the bus. Shutdown lets jobs in hand finish.
+	outbox := services.NewOutboxService(gdb, queue)
+	bus := events.NewBus(outbox, orderService.OrderFacts)
+	orderService.Events = bus
+	notifications.Subscribe(bus)
+	lifecycle.Workers("order-worker", cfg.QueueWorkers, func(ctx context.Context) {
+		queue.Work(ctx, bus.Dispatch)
 	})
+	lifecycle.Go("outbox-relay", outbox.Relay)
+
+	// Blob storage for uploaded product images
+	var blobs storage.BlobStore
+	switch cfg.StorageDriver {
+	case "s3":
+		s3, err := storage.NewS3Store(cfg.S3Endpoint, cfg.S3Bucket, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey)
+		if err != nil {
+			log.Fatalf("storage: %v", err)
+		}
+		blobs = s3
+	default:
+		local, err := storage.NewLocalStore(cfg.StorageDir)
+		if err != nil {
+			log.Fatalf("storage: %v", err)
+		}
+		blobs = local
+	}
+	imageService := &services.ImageService{
+		DB:       gdb,
+		Store:    blobs,
+		MaxBytes: cfg.MaxUploadBytes,
+		BaseURL:  cfg.PublicBaseURL,
+	}
+
+	// Inventory: stock ledger + reservation reaper for unpaid orders
+	inventory := &services.InventoryService{
+		DB:             gdb,
+		ReservationTTL: cfg.ReservationTTL,
+		Notifications:  notifications,
+		Orders:         orderService,
+		Events:         bus,
+	}
+	lifecycle.Go("reservation-reaper", func(ctx context.Context) { inventory.RunReaper(ctx, time.Minute) })
+	orderService.Inventory = inventory
+	lifecycle.Go("order-auto-reject", func(ctx context.Context) { orderService.RunAutoReject(ctx, 30*time.Second) })
+
+	// Price history + scheduler for future-dated price changes
+	prices := &services.PriceService{DB: gdb}
+	lifecycle.Go("price-scheduler", func(ctx context.Context) { prices.RunScheduler(ctx, 30*time.Second) })
+
+	// Taxes and fees per restaurant
+	pricingService := &services.PricingService{DB: gdb}
+	coupons := &services.CouponService{DB: gdb}
+	orderService.Coupons = coupons
+
+	// Order events from every instance, for GraphQL subscriptions
+	orderFeed := services.NewOrderFeed()
+	lifecycle.Go("order-feed", orderFeed.Run)
+
+	// Idempotency keys for checkout and payments live in Redis
+	idem := idempotency.NewStore(redis.RDB, cfg.IdempotencyTTL)
 
 	// ✅ Step 3: Inject everything into resolver
 	res := &resolvers.Resolver{
 		DB:        gdb,
 		JWTSecret: os.Getenv("JWT_SECRET"),
 		CheckoutService: &services.CheckoutService{
-			DB:    gdb,
-			Redis: redis.RedisClient{},
-			Queue: queue,
+			DB:          gdb,
+			Events:      bus,
+			Inventory:   inventory,
+			Pricing:     pricingService,
+			Coupons:     coupons,
+			Orders:      orderService,
+			Idempotency: idem,
+		},
+		ImageService:  imageService,
+		Inventory:     inventory,
+		Notifications: notifications,
+		Prices:        prices,
+		Pricing:       pricingService,
+		Coupons:       coupons,
+		Carts: &services.CartService{
+			MergeStrategy: cfg.CartMergeStrategy,
+			MaxQuantity:   cfg.MaxCartQuantity,
 		},
+		Favorites:   &services.FavoritesService{DB: gdb},
+		Orders:      orderService,
+		OrderFeed:   orderFeed,
+		OrderJobs:   orderJobs,
+		Events:      bus,
+		Idempotency: idem,
+
+		MaxCartQuantity: cfg.MaxCartQuantity,
 	}
 
-	srv := handler.NewDefaultServer(
-		gql.NewExecutableSchema(gql.Config{Resolvers: res}),
-	)
+	srv := handler.New(gql.NewExecutableSchema(gql.Config{Resolvers: res}))
+
+	// Subscriptions: websocket connections authenticate in connection_init,
+	// since browsers can't set headers on the upgrade request
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || slices.Contains(allowedOrigins, origin)
+			},
+		},
+		InitFunc: func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+			ctx, err := custommiddleware.WithBearerToken(ctx, payload.Authorization())
+			return ctx, nil, err
+		},
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](100)})
 
 	r := chi.NewRouter()
 
 	// ✅ Step 4: ADD CORS middleware BEFORE JWT
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000", "https://swiggy-frontend1.vercel.app"}, // frontend dev URL
+		AllowedOrigins:   allowedOrigins, // frontend dev URL
 		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "Last-Event-ID", custommiddleware.GuestHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Keep stream tokens passed in the URL out of the request log
+	r.Use(custommiddleware.StripAccessToken)
 	r.Use(middleware.Logger)
 
 	// ✅ JWT-protected GraphQL endpoint
 	r.Handle("/query", custommiddleware.JWT(srv))
 
+	// Product images: upload (admins) and public download with cache headers
+	images := &handlers.ImageHandler{DB: gdb, Images: imageService}
+	r.With(handlers.LimitBody(cfg.MaxUploadBytes+1<<20)).
+		Method(http.MethodPost, "/images", custommiddleware.JWT(http.HandlerFunc(images.Upload)))
+	r.Get("/images/{id}", images.Serve)
+
+	// Order tracking over Server-Sent Events; authenticates on its own since
+	// EventSource can't send an Authorization header
+	orderEvents := &handlers.OrderEventsHandler{DB: gdb, Orders: orderService, Feed: orderFeed, Closing: ctx.Done()}
+	r.Get("/orders/{id}/events", orderEvents.Stream)
+
 	// GraphQL Playground
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		playground.Handler("GraphQL playground", "/query").ServeHTTP(w, r)
 	})
 
-	log.Println(" Listening on :" + cfg.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Port, r))
+	// Liveness and readiness for the load balancer; readiness turns false as
+	// soon as shutdown starts
+	r.Get("/healthz", lifecycle.Live)
+	r.Get("/readyz", lifecycle.Ready)
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := lifecycle.Run(server); err != nil {
+		log.Fatalf("shutdown: %v", err)
+	}
 }
@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,62 +32,114 @@ func JWT(next http.Handler) http.Handler {
 		}
 		r.Body = io.NopCloser(&buf)
 
+		// A valid guest session rides along on every request: anonymous
+		// shoppers use it for their cart, and login/signup merge that cart.
+		// It never stands in for a user outside the cart (see below).
+		guestCtx, isGuest := withGuest(r.Context(), r.Header.Get(GuestHeader))
+		r = r.WithContext(guestCtx)
+
 		bodyStr := string(bodyBytes)
-		if strings.Contains(bodyStr, "login") || strings.Contains(bodyStr, "signup") || strings.Contains(bodyStr, "IntrospectionQuery") {
+		if strings.Contains(bodyStr, "login") || strings.Contains(bodyStr, "signup") || strings.Contains(bodyStr, "startGuestSession") || strings.Contains(bodyStr, "IntrospectionQuery") {
 			fmt.Println("[JWT MIDDLEWARE] Public operation detected, skipping auth")
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// Websocket upgrades carry no headers worth checking; the token comes
+		// in connection_init instead (see WithBearerToken)
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// JWT processing
 		authHeader := r.Header.Get("Authorization")
 		// fmt.Println("[JWT MIDDLEWARE] Authorization header =", authHeader)
 
-		if authHeader == "" {
-			// fmt.Println("[JWT MIDDLEWARE ] Missing Authorization header")
-			http.Error(w, "missing auth header", http.StatusUnauthorized)
+		// Guests get through without a token, but only for cart operations
+		if authHeader == "" && isGuest && isGuestOperation(bodyBytes) {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			// fmt.Println("[JWT MIDDLEWARE ] Invalid Authorization header format")
-			http.Error(w, "invalid token format", http.StatusUnauthorized)
+		ctx, err := WithBearerToken(r.Context(), authHeader)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		tokenStr := parts[1]
-		// fmt.Println("[JWT MIDDLEWARE] Token string =", tokenStr)
-		// fmt.Println("[JWT MIDDLEWARE] JWT_SECRET =", os.Getenv("JWT_SECRET"))
-
-		claims := jwt.RegisteredClaims{}
-		token, err := jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+const accessTokenKey ctxKey = "access_token"
 
-		if err != nil {
-			// fmt.Println("[JWT MIDDLEWARE ] Token parsing error:", err)
-			http.Error(w, "invalid token", http.StatusUnauthorized)
-			return
-		}
-		if !token.Valid {
-			// fmt.Println("[JWT MIDDLEWARE ] Token is NOT valid")
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+// StripAccessToken takes an access_token query parameter off the URL before
+// anything logs it and keeps it in the context for the handlers that accept
+// one (see AccessTokenFromCtx). It must run ahead of the request logger.
+func StripAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if !q.Has("access_token") {
+			next.ServeHTTP(w, r)
 			return
 		}
+		token := q.Get("access_token")
+		q.Del("access_token")
+		r = r.WithContext(context.WithValue(r.Context(), accessTokenKey, token))
+		r.URL.RawQuery = q.Encode()
+		r.RequestURI = r.URL.RequestURI()
+		next.ServeHTTP(w, r)
+	})
+}
 
-		if claims.Subject == "" {
-			// fmt.Println("[JWT MIDDLEWARE ] Token subject is missing")
-			http.Error(w, "no subject in token", http.StatusUnauthorized)
-			return
-		}
+// AccessTokenFromCtx returns the access_token query parameter that
+// StripAccessToken took off the request, if there was one
+func AccessTokenFromCtx(ctx context.Context) string {
+	token, _ := ctx.Value(accessTokenKey).(string)
+	return token
+}
 
-		//  Token is valid
-		// fmt.Println("[JWT MIDDLEWARE ] Token is valid. Subject:", claims.Subject)
+// WithBearerToken checks a "Bearer <jwt>" Authorization value and adds the
+// user ID to ctx. Websocket connections pass theirs in connection_init.
+func WithBearerToken(ctx context.Context, authHeader string) (context.Context, error) {
+	if authHeader == "" {
+		// fmt.Println("[JWT MIDDLEWARE ] Missing Authorization header")
+		return ctx, errors.New("missing auth header")
+	}
 
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.Subject)
-		next.ServeHTTP(w, r.WithContext(ctx))
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		// fmt.Println("[JWT MIDDLEWARE ] Invalid Authorization header format")
+		return ctx, errors.New("invalid token format")
+	}
+
+	tokenStr := parts[1]
+	// fmt.Println("[JWT MIDDLEWARE] Token string =", tokenStr)
+	// fmt.Println("[JWT MIDDLEWARE] JWT_SECRET =", os.Getenv("JWT_SECRET"))
+
+	claims := jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
 	})
+
+	if err != nil {
+		// fmt.Println("[JWT MIDDLEWARE ] Token parsing error:", err)
+		return ctx, errors.New("invalid token")
+	}
+	if !token.Valid {
+		// fmt.Println("[JWT MIDDLEWARE ] Token is NOT valid")
+		return ctx, errors.New("invalid token")
+	}
+
+	if claims.Subject == "" {
+		// fmt.Println("[JWT MIDDLEWARE ] Token subject is missing")
+		return ctx, errors.New("no subject in token")
+	}
+
+	//  Token is valid
+	// fmt.Println("[JWT MIDDLEWARE ] Token is valid. Subject:", claims.Subject)
+
+	return context.WithValue(ctx, UserIDKey, claims.Subject), nil
 }
 
 func UserIDFromCtx(ctx context.Context) (uint, bool) {
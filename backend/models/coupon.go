@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type CouponType string
+
+const (
+	CouponPercentage   CouponType = "PERCENTAGE"
+	CouponFlat         CouponType = "FLAT"
+	CouponFreeDelivery CouponType = "FREE_DELIVERY"
+)
+
+// Coupon is a promo code. RestaurantID is the admin whose items it applies
+// to; nil means any restaurant. Zero limits mean "no limit".
+type Coupon struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Code        string     `gorm:"type:varchar(40);uniqueIndex;not null" json:"code"` // stored upper-case
+	Description string     `json:"description"`
+	Type        CouponType `gorm:"type:varchar(20);not null" json:"type"`
+	PercentBps  int64      `gorm:"not null;default:0" json:"percentBps"`
+	FlatAmount  Money      `gorm:"not null;default:0" json:"flatAmount"`
+	MinOrder    Money      `gorm:"not null;default:0" json:"minOrder"`
+	MaxDiscount Money      `gorm:"not null;default:0" json:"maxDiscount"`
+
+	UsageLimit     int  `gorm:"not null;default:0" json:"usageLimit"`   // across all users
+	PerUserLimit   int  `gorm:"not null;default:0" json:"perUserLimit"` // per user
+	UsedCount      int  `gorm:"not null;default:0" json:"usedCount"`
+	FirstOrderOnly bool `gorm:"not null;default:false" json:"firstOrderOnly"`
+
+	RestaurantID *uint      `gorm:"index" json:"restaurantId,omitempty"`
+	ValidFrom    *time.Time `json:"validFrom,omitempty"`
+	ValidUntil   *time.Time `json:"validUntil,omitempty"`
+	Active       bool       `gorm:"not null;default:true" json:"active"`
+
+	CreatedBy uint      `gorm:"not null" json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CouponRedemption records a coupon used on an order
+type CouponRedemption struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CouponID  uint      `gorm:"not null;index" json:"couponId"`
+	UserID    uint      `gorm:"not null;index" json:"userId"`
+	OrderID   uint      `gorm:"not null;uniqueIndex" json:"orderId"`
+	Discount  Money     `gorm:"not null" json:"discount"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ImageAsset is an uploaded image. Product.Image holds its ID; the bytes
+// (original + thumbnails) live in the configured BlobStore.
+type ImageAsset struct {
+	ID          string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	OwnerID     uint      `gorm:"not null;index" json:"owner_id"`
+	ContentType string    `gorm:"type:varchar(50);not null" json:"content_type"`
+	Size        int64     `gorm:"not null" json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	CreatedAt   time.Time `json:"created_at"`
+}
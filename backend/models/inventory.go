@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// MovementReason says why a product's stock changed
+type MovementReason string
+
+const (
+	MovementOrder        MovementReason = "ORDER"
+	MovementRestock      MovementReason = "RESTOCK"
+	MovementAdjustment   MovementReason = "ADJUSTMENT"
+	MovementCancellation MovementReason = "CANCELLATION"
+)
+
+// InventoryMovement is one row in the stock ledger. Product.Stock always
+// equals the sum of all deltas for that product.
+type InventoryMovement struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	ProductID  uint           `gorm:"not null;index" json:"product_id"`
+	Delta      int            `gorm:"not null" json:"delta"`
+	StockAfter int            `gorm:"not null" json:"stock_after"`
+	Reason     MovementReason `gorm:"type:varchar(20);not null" json:"reason"`
+	OrderID    *uint          `gorm:"index" json:"order_id,omitempty"`
+	ActorID    *uint          `json:"actor_id,omitempty"`
+	Note       string         `json:"note"`
+	CreatedAt  time.Time      `gorm:"index" json:"created_at"`
+}
+
+// ReservationStatus tracks what happened to a stock hold
+type ReservationStatus string
+
+const (
+	ReservationActive   ReservationStatus = "ACTIVE"
+	ReservationConsumed ReservationStatus = "CONSUMED" // payment went through
+	ReservationReleased ReservationStatus = "RELEASED" // payment failed, or the order was rejected or cancelled
+	ReservationExpired  ReservationStatus = "EXPIRED"  // customer never paid
+)
+
+// StockReservation holds stock for an unpaid order while the customer is on
+// the payment page. Stock is taken at checkout; if the reservation is released
+// or expires, the quantity goes back on the shelf.
+type StockReservation struct {
+	ID        uint              `gorm:"primaryKey" json:"id"`
+	UserID    uint              `gorm:"not null;index" json:"user_id"`
+	OrderID   uint              `gorm:"not null;index" json:"order_id"`
+	ProductID uint              `gorm:"not null;index" json:"product_id"`
+	Quantity  int               `gorm:"not null" json:"quantity"`
+	Status    ReservationStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	ExpiresAt time.Time         `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
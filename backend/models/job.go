@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// QueueJob is a unit of background work waiting in a Postgres-backed queue.
+// A worker claims a job by pushing RunAt past its visibility timeout; if the
+// worker dies the job simply becomes due again.
+type QueueJob struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Queue       string         `gorm:"type:varchar(50);not null;index:idx_queue_jobs_due,priority:1" json:"queue"`
+	EventID     string         `gorm:"type:varchar(64);uniqueIndex" json:"event_id"` // a repeated publish is dropped
+	EventType   string         `gorm:"type:varchar(50);not null;default:'order.placed'" json:"event_type"`
+	OrderID     uint           `gorm:"not null;index" json:"order_id"` // 0 for events not about an order
+	Payload     datatypes.JSON `json:"payload"`                        // the whole encoded event
+	Attempts    int            `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int            `gorm:"not null" json:"max_attempts"`
+	RunAt       time.Time      `gorm:"not null;index:idx_queue_jobs_due,priority:2" json:"run_at"`
+	LastError   *string        `json:"last_error,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// DeadJob is a job that ran out of attempts. It stays here until an admin
+// replays it, which queues a fresh copy and sets ReplayedAt.
+type DeadJob struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	Queue      string         `gorm:"type:varchar(50);not null;index" json:"queue"`
+	EventID    string         `gorm:"type:varchar(64)" json:"event_id"`
+	EventType  string         `gorm:"type:varchar(50);not null;default:'order.placed'" json:"event_type"`
+	OrderID    uint           `gorm:"not null;index" json:"order_id"`
+	Payload    datatypes.JSON `json:"payload"`
+	Attempts   int            `gorm:"not null" json:"attempts"`
+	LastError  string         `gorm:"not null" json:"last_error"`
+	EnqueuedAt time.Time      `gorm:"not null" json:"enqueued_at"`
+	FailedAt   time.Time      `gorm:"not null;index" json:"failed_at"`
+	ReplayedAt *time.Time     `json:"replayed_at,omitempty"`
+	ReplayedBy *uint          `json:"replayed_by,omitempty"`
+}
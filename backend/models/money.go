@@ -0,0 +1,300 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the only currency the shop trades in today. Columns
+// store minor units only; the currency is implied.
+const DefaultCurrency = "INR"
+
+// Money is an exact amount in minor units (paise for INR). Use it for every
+// price, total and payment instead of float64.
+type Money struct {
+	Amount   int64  // minor units
+	Currency string // ISO 4217 code, DefaultCurrency when empty
+}
+
+// NewMoney builds a Money from minor units
+func NewMoney(minor int64) Money {
+	return Money{Amount: minor, Currency: DefaultCurrency}
+}
+
+// ParseMoney parses a decimal string such as "249", "249.5" or "249.50"
+// without going through float64. One leading sign is allowed; everything
+// else must be digits around at most one point. More than two decimals is
+// an error.
+func ParseMoney(s string) (Money, error) {
+	input := s
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	if neg || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("invalid money amount %q", input)
+	}
+	if len(frac) > 2 {
+		return Money{}, fmt.Errorf("money amount %q has more than 2 decimal places", input)
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > (math.MaxInt64-99)/100 {
+		return Money{}, fmt.Errorf("invalid money amount %q", input)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid money amount %q", input)
+	}
+
+	amount := units*100 + cents
+	if neg {
+		amount = -amount
+	}
+	return NewMoney(amount), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MoneyFromFloat rounds a float to the nearest minor unit. Only for reading
+// legacy data (old JSON snapshots, float columns); never for arithmetic.
+func MoneyFromFloat(f float64) Money {
+	return NewMoney(int64(math.Round(f * 100)))
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// sameCurrency returns the currency m and o share. Amounts in different
+// currencies can't be combined, and doing so is a programming error.
+func (m Money) sameCurrency(o Money) string {
+	if m.currency() != o.currency() {
+		panic(fmt.Sprintf("models: can't combine %s and %s amounts", m.currency(), o.currency()))
+	}
+	return m.currency()
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.sameCurrency(o)}
+}
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.sameCurrency(o)}
+}
+
+// Mul multiplies by a whole quantity
+func (m Money) Mul(qty int) Money {
+	return Money{Amount: m.Amount * int64(qty), Currency: m.currency()}
+}
+
+// Percent returns m * bps / 10000 rounded half away from zero
+// (1% = 100 basis points)
+func (m Money) Percent(bps int64) Money {
+	p := m.Amount * bps
+	q := p / 10000
+	if r := p % 10000; r*2 >= 10000 {
+		q++
+	} else if r*2 <= -10000 {
+		q--
+	}
+	return Money{Amount: q, Currency: m.currency()}
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Min returns the smaller of m and o
+func (m Money) Min(o Money) Money {
+	cur := m.sameCurrency(o)
+	if o.Amount < m.Amount {
+		return Money{Amount: o.Amount, Currency: cur}
+	}
+	return m
+}
+
+// Allocate splits m across weights so the parts always add back up to m
+// exactly. Each part gets its floor share; leftover minor units go to the
+// parts with the largest remainders, ties broken by position, so the same
+// input always gives the same split. A negative amount splits like its
+// absolute value, negated.
+func (m Money) Allocate(weights []int64) []Money {
+	if m.Amount < 0 {
+		parts := Money{Amount: -m.Amount, Currency: m.Currency}.Allocate(weights)
+		for i := range parts {
+			parts[i].Amount = -parts[i].Amount
+		}
+		return parts
+	}
+	parts := make([]Money, len(weights))
+	var total int64
+	for _, w := range weights {
+		total += w
+	}
+	if len(weights) == 0 {
+		return parts
+	}
+	if total <= 0 {
+		// Nothing to weigh by: give everything to the first part
+		parts[0] = m
+		for i := 1; i < len(parts); i++ {
+			parts[i] = NewMoney(0)
+		}
+		return parts
+	}
+
+	remainders := make([]int64, len(weights))
+	var allocated int64
+	for i, w := range weights {
+		share := m.Amount * w
+		parts[i] = Money{Amount: share / total, Currency: m.currency()}
+		remainders[i] = share % total
+		allocated += parts[i].Amount
+	}
+
+	for left := m.Amount - allocated; left > 0; left-- {
+		best := 0
+		for i := 1; i < len(remainders); i++ {
+			if remainders[i] > remainders[best] {
+				best = i
+			}
+		}
+		parts[best].Amount++
+		remainders[best] = -1 // each part gets at most one extra unit
+	}
+	return parts
+}
+
+// String formats as a plain decimal, e.g. "249.50"
+func (m Money) String() string {
+	a := m.Amount
+	sign := ""
+	if a < 0 {
+		sign = "-"
+		a = -a
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, a/100, a%100)
+}
+
+// Float is for display/logging only
+func (m Money) Float() float64 { return float64(m.Amount) / 100 }
+
+// ---------- database ----------
+
+// GormDataType stores money as bigint minor units
+func (Money) GormDataType() string { return "bigint" }
+
+func (m Money) Value() (driver.Value, error) { return m.Amount, nil }
+
+func (m *Money) Scan(v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		*m = NewMoney(0)
+	case int64:
+		*m = NewMoney(x)
+	case float64:
+		*m = NewMoney(int64(math.Round(x)))
+	case []byte:
+		return m.scanString(string(x))
+	case string:
+		return m.scanString(x)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", v)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Money: %w", s, err)
+	}
+	*m = NewMoney(n)
+	return nil
+}
+
+// ---------- JSON / GraphQL ----------
+// On the wire money is a decimal number in major units (249.50), which is
+// what clients already expect from the old Float fields.
+
+func (m Money) MarshalJSON() ([]byte, error) { return []byte(m.String()), nil }
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" || s == "" {
+		*m = NewMoney(0)
+		return nil
+	}
+	parsed, err := parseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// MarshalGQL implements graphql.Marshaler
+func (m Money) MarshalGQL(w io.Writer) { io.WriteString(w, m.String()) }
+
+// UnmarshalGQL implements graphql.Unmarshaler
+func (m *Money) UnmarshalGQL(v interface{}) error {
+	var (
+		parsed Money
+		err    error
+	)
+	switch x := v.(type) {
+	case string:
+		parsed, err = ParseMoney(x)
+	case json.Number:
+		parsed, err = ParseMoney(x.String())
+	case int:
+		parsed = NewMoney(int64(x) * 100)
+	case int64:
+		parsed = NewMoney(x * 100)
+	case float64:
+		parsed, err = ParseMoney(strconv.FormatFloat(x, 'f', -1, 64))
+	default:
+		return fmt.Errorf("Money must be a number or decimal string, got %T", v)
+	}
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// parseDecimal accepts JSON number syntax. Old snapshots may hold float
+// artefacts like 99.99000000000001, so anything past two decimals is rounded.
+func parseDecimal(s string) (Money, error) {
+	if parsed, err := ParseMoney(s); err == nil {
+		return parsed, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid money amount %q", s)
+	}
+	return MoneyFromFloat(f), nil
+}
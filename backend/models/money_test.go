@@ -0,0 +1,190 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "249", want: 24900},
+		{in: "249.5", want: 24950},
+		{in: "249.50", want: 24950},
+		{in: "0.05", want: 5},
+		{in: ".5", want: 50},
+		{in: "5.", want: 500},
+		{in: " 12.34 ", want: 1234},
+		{in: "+1.25", want: 125},
+		{in: "-1.25", want: -125},
+		{in: "-0.01", want: -1},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "1.234", wantErr: true},
+		{in: "1.-5", wantErr: true},
+		{in: "1.+5", wantErr: true},
+		{in: "--1", wantErr: true},
+		{in: "-+1", wantErr: true},
+		{in: "+-1", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "12a", wantErr: true},
+		{in: "1 000", wantErr: true},
+		{in: "92233720368547758.07", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q): %v", tt.in, err)
+			}
+			if got.Amount != tt.want || got.Currency != DefaultCurrency {
+				t.Errorf("ParseMoney(%q) = %+v, want %d %s", tt.in, got, tt.want, DefaultCurrency)
+			}
+		})
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{"even split", 300, []int64{1, 1, 1}, []int64{100, 100, 100}},
+		{"remainder to largest remainders", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"by weight", 1000, []int64{3, 1}, []int64{750, 250}},
+		{"uneven weights", 1001, []int64{2, 3, 5}, []int64{200, 300, 501}},
+		{"negative even split", -300, []int64{1, 1, 1}, []int64{-100, -100, -100}},
+		{"negative remainder", -100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{"negative by weight", -1001, []int64{2, 3, 5}, []int64{-200, -300, -501}},
+		{"zero amount", 0, []int64{1, 2}, []int64{0, 0}},
+		{"no weight goes to the first part", 500, []int64{0, 0}, []int64{500, 0}},
+		{"negative with no weight", -500, []int64{0, 0}, []int64{-500, 0}},
+		{"single part", -7, []int64{4}, []int64{-7}},
+		{"no parts", 100, nil, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := NewMoney(tt.amount).Allocate(tt.weights)
+			if len(parts) != len(tt.want) {
+				t.Fatalf("got %d parts, want %d", len(parts), len(tt.want))
+			}
+			var sum int64
+			for i, p := range parts {
+				sum += p.Amount
+				if p.Amount != tt.want[i] {
+					t.Errorf("part %d = %d, want %d", i, p.Amount, tt.want[i])
+				}
+			}
+			if len(parts) > 0 && sum != tt.amount {
+				t.Errorf("parts add up to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := []struct {
+		amount, bps, want int64
+	}{
+		{10000, 500, 500},   // 5% of 100.00
+		{999, 500, 50},      // 49.95 rounds up
+		{989, 500, 49},      // 49.45 rounds down
+		{-999, 500, -50},    // half away from zero
+		{12345, 1800, 2222}, // 18% GST
+		{100, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := NewMoney(tt.amount).Percent(tt.bps); got.Amount != tt.want {
+			t.Errorf("%d.Percent(%d) = %d, want %d", tt.amount, tt.bps, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := map[int64]string{
+		0:      "0.00",
+		5:      "0.05",
+		24950:  "249.50",
+		-1:     "-0.01",
+		-24950: "-249.50",
+	}
+	for amount, want := range tests {
+		if got := NewMoney(amount).String(); got != want {
+			t.Errorf("NewMoney(%d).String() = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{`249.5`, 24950},
+		{`"249.50"`, 24950},
+		{`99.99000000000001`, 9999}, // float artefact in an old snapshot
+		{`null`, 0},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := json.Unmarshal([]byte(tt.in), &m); err != nil {
+			t.Fatalf("unmarshal %s: %v", tt.in, err)
+		}
+		if m.Amount != tt.want {
+			t.Errorf("unmarshal %s = %d, want %d", tt.in, m.Amount, tt.want)
+		}
+	}
+
+	out, err := json.Marshal(struct{ Price Money }{NewMoney(24950)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"Price":249.50}` {
+		t.Errorf("marshal = %s", out)
+	}
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	inr, usd := NewMoney(500), Money{Amount: 500, Currency: "USD"}
+	unset := Money{Amount: 500} // no currency means the default
+
+	tests := []struct {
+		name      string
+		op        func() Money
+		want      int64
+		wantPanic bool
+	}{
+		{"add same currency", func() Money { return inr.Add(inr) }, 1000, false},
+		{"add to an unset currency", func() Money { return unset.Add(inr) }, 1000, false},
+		{"sub same currency", func() Money { return inr.Sub(unset) }, 0, false},
+		{"min same currency", func() Money { return inr.Min(NewMoney(200)) }, 200, false},
+		{"add another currency", func() Money { return inr.Add(usd) }, 0, true},
+		{"sub another currency", func() Money { return usd.Sub(inr) }, 0, true},
+		{"min another currency", func() Money { return inr.Min(usd) }, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if p := recover(); (p != nil) != tt.wantPanic {
+					t.Errorf("panic = %v, want panic %v", p, tt.wantPanic)
+				}
+			}()
+			if got := tt.op(); got.Amount != tt.want {
+				t.Errorf("got %d, want %d", got.Amount, tt.want)
+			}
+		})
+	}
+}
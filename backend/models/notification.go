@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type NotificationKind string
+
+const (
+	NotificationLowStock   NotificationKind = "LOW_STOCK"
+	NotificationOutOfStock NotificationKind = "OUT_OF_STOCK"
+	NotificationNewOrder   NotificationKind = "NEW_ORDER"
+	NotificationOrderPaid  NotificationKind = "ORDER_PAID"
+)
+
+// Notification is an in-app message for a user (mostly restaurant admins)
+type Notification struct {
+	ID        uint             `gorm:"primaryKey" json:"id"`
+	UserID    uint             `gorm:"not null;index;uniqueIndex:idx_notifications_event,priority:2" json:"user_id"`
+	Kind      NotificationKind `gorm:"type:varchar(30);not null" json:"kind"`
+	Message   string           `gorm:"not null" json:"message"`
+	ProductID *uint            `json:"product_id,omitempty"`
+	EventID   *string          `gorm:"type:varchar(64);uniqueIndex:idx_notifications_event,priority:1" json:"-"` // the event that caused it, so a redelivery doesn't notify twice
+	ReadAt    *time.Time       `json:"read_at,omitempty"`
+	CreatedAt time.Time        `gorm:"index" json:"created_at"`
+}
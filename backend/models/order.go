@@ -11,27 +11,85 @@ import (
 // OrderStatus is typed to make status usage consistent across codebase
 type OrderStatus string
 
+// The lifecycle runs PLACED → ACCEPTED → PREPARING → READY → PICKED_UP →
+// DELIVERED. CANCELLED and REJECTED end it early. Which moves are allowed is
+// decided by services.OrderService.
 const (
-	OrderPending    OrderStatus = "PENDING"
-	OrderProcessing OrderStatus = "PROCESSING"
-	OrderSuccess    OrderStatus = "SUCCESS"
-	OrderFailed     OrderStatus = "FAILED"
+	OrderPlaced    OrderStatus = "PLACED"
+	OrderAccepted  OrderStatus = "ACCEPTED"
+	OrderPreparing OrderStatus = "PREPARING"
+	OrderReady     OrderStatus = "READY"
+	OrderPickedUp  OrderStatus = "PICKED_UP"
+	OrderDelivered OrderStatus = "DELIVERED"
+	OrderCancelled OrderStatus = "CANCELLED"
+	OrderRejected  OrderStatus = "REJECTED"
 )
 
+// Terminal reports whether no further status change is possible
+func (s OrderStatus) Terminal() bool {
+	return s == OrderDelivered || s == OrderCancelled || s == OrderRejected
+}
+
+// Who changed an order's status
+type OrderActor string
+
+const (
+	ActorUser   OrderActor = "USER"
+	ActorAdmin  OrderActor = "ADMIN"
+	ActorSystem OrderActor = "SYSTEM"
+)
+
+// OrderStatusHistory is the audit trail of an order: one row per status
+// change, including the initial PLACED (FromStatus empty)
+type OrderStatusHistory struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	OrderID    uint        `gorm:"not null;index" json:"orderId"`
+	FromStatus OrderStatus `gorm:"type:varchar(20)" json:"fromStatus"`
+	ToStatus   OrderStatus `gorm:"type:varchar(20);not null" json:"toStatus"`
+	ActorType  OrderActor  `gorm:"type:varchar(10);not null" json:"actorType"`
+	ActorID    *uint       `json:"actorId,omitempty"` // nil for SYSTEM
+	Reason     string      `json:"reason"`
+	CreatedAt  time.Time   `gorm:"index" json:"createdAt"`
+}
+
+func (OrderStatusHistory) TableName() string { return "order_status_history" }
+
 // Order represents a stored order.
 // Products is stored as JSONB (snapshot of product details at purchase time).
 
 type Order struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
-	UserID         uint           `json:"user_id"`
+	UserID         uint           `gorm:"uniqueIndex:idx_orders_user_idempotency_key,priority:1" json:"user_id"`
 	Products       datatypes.JSON `gorm:"type:jsonb" json:"products"`
 	ProductAdmins  pq.StringArray `gorm:"type:text[]" json:"product_admins"` // ✅ FIXED
-	Total          float64        `json:"total"`
+	Total          Money          `json:"total"`
 	Status         OrderStatus    `gorm:"type:varchar(20)" json:"status"`
 	PlacedAt       time.Time      `json:"placed_at"`
 	Items          []OrderItem    `gorm:"foreignKey:OrderID" json:"items"`
-	IdempotencyKey *string        `json:"idempotency_key,omitempty"`
+	IdempotencyKey *string        `gorm:"uniqueIndex:idx_orders_user_idempotency_key,priority:2" json:"idempotency_key,omitempty"`
+	Bill           datatypes.JSON `gorm:"type:jsonb" json:"bill"` // pricing.Bill at checkout time
+	PrepMinutes    *int           `json:"prep_minutes,omitempty"` // set when the restaurant accepts
+	AcceptedAt     *time.Time     `json:"accepted_at,omitempty"`
+	ReadyBy        *time.Time     `json:"ready_by,omitempty"`                      // AcceptedAt + PrepMinutes
+	CancelReason   *string        `json:"cancel_reason,omitempty"`                 // why it was cancelled or rejected
+	RefundAmount   Money          `gorm:"not null;default:0" json:"refund_amount"` // paid back to the customer
+	LegacyStatus   *string        `json:"legacy_status,omitempty"`                 // status before the lifecycle existed, if the order is that old
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
+
+// ProductSnapshot is what Order.Products stores for each line: the product as
+// it was at purchase time plus the quantity bought.
+type ProductSnapshot struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Price     Money     `json:"price"`
+	Stock     int       `json:"stock"`
+	AdminID   uint      `json:"adminId"`
+	Category  string    `json:"category,omitempty"`
+	Image     *string   `json:"image"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
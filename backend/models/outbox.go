@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// OutboxEvent is an event waiting to be handed to the queue. It is
+// written in the same transaction as the change it describes, so the event
+// exists exactly when the change does; the relay delivers it afterwards.
+type OutboxEvent struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	EventID       string         `gorm:"type:varchar(64);not null;uniqueIndex" json:"event_id"` // deduplication ID, kept on every redelivery
+	EventType     string         `gorm:"type:varchar(50);not null" json:"event_type"`
+	OrderID       uint           `gorm:"not null;index" json:"order_id"` // 0 for events not about an order
+	Payload       datatypes.JSON `json:"payload"`                        // the whole encoded event; NULL in rows from before it existed
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`
+	LastError     *string        `json:"last_error,omitempty"`
+	NextAttemptAt time.Time      `gorm:"not null;index" json:"next_attempt_at"`
+	DeliveredAt   *time.Time     `gorm:"index" json:"delivered_at,omitempty"`
+	DeadAt        *time.Time     `json:"dead_at,omitempty"` // set once the relay gives up; kept for inspection, never pruned
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+func (OutboxEvent) TableName() string { return "outbox" }
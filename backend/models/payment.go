@@ -4,13 +4,23 @@ import (
 	"time"
 )
 
+// Payment statuses set after the fact, when an order is rejected or cancelled
+const (
+	PaymentSuccess           = "SUCCESS"
+	PaymentRefunded          = "REFUNDED"
+	PaymentPartiallyRefunded = "PARTIALLY_REFUNDED"
+)
+
 type Payment struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    string    `json:"user_id"`
-	AdminID   string    `json:"admin_id"`
-	OrderID   string    `json:"order_id"`
-	Amount    float64   `json:"amount"`
-	Status    string    `json:"status"` // SUCCESS, PENDING, FAILED
-	Method    string    `json:"method"` // CARD, UPI, COD
-	CreatedAt time.Time `json:"created_at"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	UserID         string    `json:"user_id"`
+	AdminID        string    `json:"admin_id"`
+	OrderID        string    `json:"order_id"`
+	Amount         Money     `json:"amount"`
+	RefundedAmount Money     `gorm:"not null;default:0" json:"refunded_amount"`
+	Status         string    `json:"status"` // SUCCESS, PENDING, FAILED, REFUNDED, PARTIALLY_REFUNDED
+	Method         string    `json:"method"` // CARD, UPI, COD
+	CreatedAt      time.Time `json:"created_at"`
+
+	IdempotencyKey *string `gorm:"index" json:"idempotency_key,omitempty"`
 }
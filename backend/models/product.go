@@ -5,7 +5,7 @@ import "time"
 type Product struct {
 	ID        uint    `gorm:"primaryKey"`
 	Name      string  `gorm:"not null"`
-	Price     float64 `gorm:"not null"`
+	Price     Money   `gorm:"not null"`
 	Stock     int     `gorm:"not null"`
 	Quantity  *string `gorm:"column:quantity"` // not 'image'
 	Image     *string `gorm:"column:image"`    // not 'quantity'
@@ -13,4 +13,15 @@ type Product struct {
 	Admin     User    `gorm:"foreignKey:AdminID"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// Low-stock alert fires when stock drops to this level. IsAvailable is
+	// switched off automatically at zero stock, which sets SoldOut, and back
+	// on when stock returns only if that is why it was off; a product an admin
+	// switched off stays off.
+	LowStockThreshold int  `gorm:"not null;default:5"`
+	IsAvailable       bool `gorm:"not null;default:true"`
+	SoldOut           bool `gorm:"not null;default:false"`
+
+	// Category decides the GST rate (see Restaurant.GSTRates)
+	Category string `gorm:"not null;default:'food'"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type PriceChangeStatus string
+
+const (
+	PriceScheduled PriceChangeStatus = "SCHEDULED"
+	PriceApplied   PriceChangeStatus = "APPLIED"
+	PriceCancelled PriceChangeStatus = "CANCELLED"
+)
+
+// ProductPrice is one entry in a product's price history. Applied rows are
+// the prices the product has actually had; scheduled rows are picked up by
+// the price scheduler once EffectiveAt passes.
+type ProductPrice struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	ProductID   uint              `gorm:"not null;index" json:"product_id"`
+	Price       Money             `gorm:"not null" json:"price"`
+	EffectiveAt time.Time         `gorm:"not null;index" json:"effective_at"`
+	Status      PriceChangeStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	CreatedBy   uint              `json:"created_by"`
+	AppliedAt   *time.Time        `json:"applied_at,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
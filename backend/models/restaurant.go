@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Restaurant holds the pricing rules of an admin's storefront. Products belong
+// to the admin, so a restaurant is looked up by AdminID. Admins without a row
+// get pricing.DefaultRules.
+type Restaurant struct {
+	ID      uint     `gorm:"primaryKey" json:"id"`
+	AdminID uint     `gorm:"uniqueIndex;not null" json:"adminId"`
+	Name    string   `json:"name"`
+	Lat     *float64 `json:"lat"`
+	Lng     *float64 `json:"lng"`
+
+	// GST by product category in basis points, e.g. {"food": 500, "beverage": 1800}
+	GSTRates      datatypes.JSON `gorm:"type:jsonb" json:"gstRates"`
+	DefaultGSTBps int64          `gorm:"not null;default:500" json:"defaultGstBps"`
+
+	PackagingFee        Money   `gorm:"not null;default:0" json:"packagingFee"`
+	DeliveryBaseFee     Money   `gorm:"not null;default:0" json:"deliveryBaseFee"`
+	DeliveryBaseKm      float64 `gorm:"not null;default:0" json:"deliveryBaseKm"`
+	DeliveryPerKm       Money   `gorm:"not null;default:0" json:"deliveryPerKm"`
+	SmallOrderThreshold Money   `gorm:"not null;default:0" json:"smallOrderThreshold"`
+	SmallOrderFee       Money   `gorm:"not null;default:0" json:"smallOrderFee"`
+	PlatformFee         Money   `gorm:"not null;default:0" json:"platformFee"`
+
+	// Closed restaurants keep their menu visible but can't take orders
+	IsOpen bool `gorm:"not null;default:true" json:"isOpen"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// SavedItem is a cart line the user moved out of the cart to buy later.
+// Price is what the item cost when it was saved, so drift can be flagged.
+type SavedItem struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_saved_user_product" json:"userId"`
+	ProductID uint      `gorm:"not null;uniqueIndex:idx_saved_user_product" json:"productId"`
+	Quantity  int       `gorm:"not null;default:1" json:"quantity"`
+	Price     Money     `gorm:"not null;default:0" json:"price"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type FavoriteKind string
+
+const (
+	FavoriteProduct    FavoriteKind = "PRODUCT"
+	FavoriteRestaurant FavoriteKind = "RESTAURANT"
+)
+
+// Favorite marks a dish (TargetID = product ID) or a restaurant (TargetID =
+// its admin's user ID, like everywhere else a restaurant is referenced)
+type Favorite struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	UserID    uint         `gorm:"not null;uniqueIndex:idx_favorite_target" json:"userId"`
+	Kind      FavoriteKind `gorm:"type:varchar(20);not null;uniqueIndex:idx_favorite_target" json:"kind"`
+	TargetID  uint         `gorm:"not null;uniqueIndex:idx_favorite_target" json:"targetId"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
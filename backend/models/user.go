@@ -14,6 +14,10 @@ type User struct {
 	Role      string `gorm:"type:varchar(10);default:'user'"`
 	Picture   *string
 	CreatedAt time.Time
+
+	// Where orders are delivered; used for the distance-based delivery fee
+	DeliveryLat *float64
+	DeliveryLng *float64
 }
 
 type Claims struct {
@@ -0,0 +1,94 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+
+	"swiggy-clone/backend/models"
+)
+
+// CouponKind matches models.CouponType
+type CouponKind string
+
+const (
+	CouponPercentage   CouponKind = "PERCENTAGE"
+	CouponFlat         CouponKind = "FLAT"
+	CouponFreeDelivery CouponKind = "FREE_DELIVERY"
+)
+
+var ErrCouponNotApplicable = errors.New("coupon does not apply to any item in the cart")
+
+// CouponRule is the part of a coupon that depends only on the bill. Usage
+// limits, validity and first-order checks need the database and are done by
+// the caller.
+type CouponRule struct {
+	Kind         CouponKind
+	PercentBps   int64        // for CouponPercentage
+	FlatAmount   models.Money // for CouponFlat
+	MinOrder     models.Money // eligible subtotal must reach this
+	MaxDiscount  models.Money // cap; zero means no cap
+	RestaurantID *uint        // only this restaurant's items count; nil for any
+}
+
+// MinOrderError means the eligible subtotal is below the coupon minimum
+type MinOrderError struct {
+	MinOrder models.Money
+	Subtotal models.Money
+}
+
+func (e *MinOrderError) Error() string {
+	return fmt.Sprintf("add items worth %s more to use this coupon (minimum order %s)",
+		e.MinOrder.Sub(e.Subtotal), e.MinOrder)
+}
+
+// CouponDiscount works out how much a coupon takes off the bill. The discount
+// never exceeds what it applies to, so the total can't go below the fees the
+// coupon doesn't cover.
+func CouponDiscount(b Bill, c CouponRule) (models.Money, error) {
+	zero := models.NewMoney(0)
+
+	subtotal, delivery := zero, zero
+	matched := false
+	for _, rc := range b.Restaurants {
+		if c.RestaurantID != nil && rc.RestaurantID != *c.RestaurantID {
+			continue
+		}
+		matched = true
+		subtotal = subtotal.Add(rc.Subtotal)
+		delivery = delivery.Add(rc.Delivery)
+	}
+	if !matched {
+		return zero, ErrCouponNotApplicable
+	}
+	if subtotal.Amount < c.MinOrder.Amount {
+		return zero, &MinOrderError{MinOrder: c.MinOrder, Subtotal: subtotal}
+	}
+
+	var discount models.Money
+	switch c.Kind {
+	case CouponPercentage:
+		discount = subtotal.Percent(c.PercentBps)
+	case CouponFlat:
+		discount = c.FlatAmount.Min(subtotal)
+	case CouponFreeDelivery:
+		discount = delivery
+	default:
+		return zero, fmt.Errorf("unknown coupon type %q", c.Kind)
+	}
+
+	if c.MaxDiscount.Amount > 0 {
+		discount = discount.Min(c.MaxDiscount)
+	}
+	if discount.IsNegative() {
+		discount = zero
+	}
+	return discount, nil
+}
+
+// WithDiscount returns the bill with a coupon discount taken off the total
+func WithDiscount(b Bill, code string, discount models.Money) Bill {
+	b.CouponCode = code
+	b.Discount = discount
+	b.Total = b.Total.Sub(discount)
+	return b
+}
@@ -0,0 +1,229 @@
+// Package pricing turns cart lines into an itemised bill. Everything here is a
+// pure function of its inputs: callers load the rules and lines, this package
+// does the arithmetic.
+package pricing
+
+import (
+	"math"
+
+	"swiggy-clone/backend/models"
+)
+
+// Location is a point on the map in decimal degrees
+type Location struct {
+	Lat float64
+	Lng float64
+}
+
+// Rules are the charges one restaurant applies to its part of an order
+type Rules struct {
+	GSTBps        map[string]int64 // GST by product category, in basis points
+	DefaultGSTBps int64            // for categories not in GSTBps
+
+	PackagingFee models.Money // once per restaurant per order
+
+	Origin          *Location // restaurant location; nil means no distance fee
+	DeliveryBaseFee models.Money
+	DeliveryBaseKm  float64      // distance covered by the base fee
+	DeliveryPerKm   models.Money // per started km beyond DeliveryBaseKm
+
+	SmallOrderThreshold models.Money // subtotals below this pay SmallOrderFee
+	SmallOrderFee       models.Money
+
+	PlatformFee models.Money // charged once per order
+}
+
+// DefaultRules apply to restaurants that haven't configured their own
+func DefaultRules() Rules {
+	return Rules{
+		GSTBps:              map[string]int64{},
+		DefaultGSTBps:       500, // 5% for restaurant food
+		PackagingFee:        models.NewMoney(0),
+		DeliveryBaseFee:     models.NewMoney(3000),
+		DeliveryBaseKm:      3,
+		DeliveryPerKm:       models.NewMoney(800),
+		SmallOrderThreshold: models.NewMoney(14900),
+		SmallOrderFee:       models.NewMoney(2500),
+		PlatformFee:         models.NewMoney(500),
+	}
+}
+
+// Line is one product in the cart
+type Line struct {
+	ProductID    uint
+	Name         string
+	Category     string
+	RestaurantID uint // the admin who owns the product
+	UnitPrice    models.Money
+	Quantity     int
+}
+
+// ItemCharge is a line with its total and GST worked out
+type ItemCharge struct {
+	ProductID    uint         `json:"productId"`
+	Name         string       `json:"name"`
+	Category     string       `json:"category"`
+	RestaurantID uint         `json:"restaurantId"`
+	UnitPrice    models.Money `json:"unitPrice"`
+	Quantity     int          `json:"quantity"`
+	LineTotal    models.Money `json:"lineTotal"`
+	GSTBps       int64        `json:"gstBps"`
+	GST          models.Money `json:"gst"`
+}
+
+// RestaurantCharge holds the fees for one restaurant's share of the order
+type RestaurantCharge struct {
+	RestaurantID  uint         `json:"restaurantId"`
+	Subtotal      models.Money `json:"subtotal"`
+	GST           models.Money `json:"gst"`
+	Packaging     models.Money `json:"packaging"`
+	Delivery      models.Money `json:"delivery"`
+	DistanceKm    *float64     `json:"distanceKm,omitempty"`
+	SmallOrderFee models.Money `json:"smallOrderFee"`
+}
+
+// Bill is the full breakdown. Total is what the customer pays, after any
+// coupon Discount (see WithDiscount).
+type Bill struct {
+	Items         []ItemCharge       `json:"items"`
+	Restaurants   []RestaurantCharge `json:"restaurants"`
+	Subtotal      models.Money       `json:"subtotal"`
+	GST           models.Money       `json:"gst"`
+	Packaging     models.Money       `json:"packaging"`
+	Delivery      models.Money       `json:"delivery"`
+	SmallOrderFee models.Money       `json:"smallOrderFee"`
+	PlatformFee   models.Money       `json:"platformFee"`
+	Discount      models.Money       `json:"discount"`
+	CouponCode    string             `json:"couponCode,omitempty"`
+	Total         models.Money       `json:"total"`
+}
+
+// Compute prices the lines. rulesFor returns the rules of a restaurant and
+// dest is the delivery location, nil when unknown. An empty cart costs nothing.
+func Compute(lines []Line, rulesFor func(restaurantID uint) Rules, dest *Location) Bill {
+	zero := models.NewMoney(0)
+	bill := Bill{
+		Items:         []ItemCharge{},
+		Restaurants:   []RestaurantCharge{},
+		Subtotal:      zero,
+		GST:           zero,
+		Packaging:     zero,
+		Delivery:      zero,
+		SmallOrderFee: zero,
+		PlatformFee:   zero,
+		Discount:      zero,
+		Total:         zero,
+	}
+	if len(lines) == 0 {
+		return bill
+	}
+
+	// Group by restaurant in the order restaurants first appear
+	byRestaurant := map[uint]int{}
+	rules := map[uint]Rules{}
+	for _, l := range lines {
+		if _, ok := byRestaurant[l.RestaurantID]; !ok {
+			byRestaurant[l.RestaurantID] = len(bill.Restaurants)
+			rules[l.RestaurantID] = rulesFor(l.RestaurantID)
+			bill.Restaurants = append(bill.Restaurants, RestaurantCharge{
+				RestaurantID:  l.RestaurantID,
+				Subtotal:      zero,
+				GST:           zero,
+				Packaging:     zero,
+				Delivery:      zero,
+				SmallOrderFee: zero,
+			})
+		}
+
+		r := rules[l.RestaurantID]
+		lineTotal := l.UnitPrice.Mul(l.Quantity)
+		bps := GSTRate(r, l.Category)
+		gst := lineTotal.Percent(bps)
+		bill.Items = append(bill.Items, ItemCharge{
+			ProductID:    l.ProductID,
+			Name:         l.Name,
+			Category:     l.Category,
+			RestaurantID: l.RestaurantID,
+			UnitPrice:    l.UnitPrice,
+			Quantity:     l.Quantity,
+			LineTotal:    lineTotal,
+			GSTBps:       bps,
+			GST:          gst,
+		})
+
+		rc := &bill.Restaurants[byRestaurant[l.RestaurantID]]
+		rc.Subtotal = rc.Subtotal.Add(lineTotal)
+		rc.GST = rc.GST.Add(gst)
+	}
+
+	for i := range bill.Restaurants {
+		rc := &bill.Restaurants[i]
+		r := rules[rc.RestaurantID]
+
+		rc.Packaging = r.PackagingFee
+		rc.SmallOrderFee = SmallOrderFee(r, rc.Subtotal)
+		if r.Origin != nil && dest != nil {
+			km := DistanceKm(*r.Origin, *dest)
+			rc.DistanceKm = &km
+		}
+		rc.Delivery = DeliveryFee(r, rc.DistanceKm)
+
+		bill.Subtotal = bill.Subtotal.Add(rc.Subtotal)
+		bill.GST = bill.GST.Add(rc.GST)
+		bill.Packaging = bill.Packaging.Add(rc.Packaging)
+		bill.Delivery = bill.Delivery.Add(rc.Delivery)
+		bill.SmallOrderFee = bill.SmallOrderFee.Add(rc.SmallOrderFee)
+
+		// One platform fee per order: the highest any restaurant asks for
+		if r.PlatformFee.Amount > bill.PlatformFee.Amount {
+			bill.PlatformFee = r.PlatformFee
+		}
+	}
+
+	bill.Total = bill.Subtotal.
+		Add(bill.GST).
+		Add(bill.Packaging).
+		Add(bill.Delivery).
+		Add(bill.SmallOrderFee).
+		Add(bill.PlatformFee)
+	return bill
+}
+
+// GSTRate returns the GST in basis points for a product category
+func GSTRate(r Rules, category string) int64 {
+	if bps, ok := r.GSTBps[category]; ok {
+		return bps
+	}
+	return r.DefaultGSTBps
+}
+
+// DeliveryFee charges the base fee plus DeliveryPerKm for every started km
+// beyond DeliveryBaseKm. An unknown distance pays the base fee only.
+func DeliveryFee(r Rules, distanceKm *float64) models.Money {
+	fee := r.DeliveryBaseFee
+	if distanceKm == nil || *distanceKm <= r.DeliveryBaseKm {
+		return fee
+	}
+	extraKm := int(math.Ceil(*distanceKm - r.DeliveryBaseKm))
+	return fee.Add(r.DeliveryPerKm.Mul(extraKm))
+}
+
+// SmallOrderFee applies when the subtotal is below the threshold
+func SmallOrderFee(r Rules, subtotal models.Money) models.Money {
+	if subtotal.Amount < r.SmallOrderThreshold.Amount {
+		return r.SmallOrderFee
+	}
+	return models.NewMoney(0)
+}
+
+// DistanceKm is the great-circle (haversine) distance between two points
+func DistanceKm(a, b Location) float64 {
+	const earthRadiusKm = 6371.0
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := rad(b.Lat - a.Lat)
+	dLng := rad(b.Lng - a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,209 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+
+	"swiggy-clone/backend/models"
+)
+
+func rs(amount int64) models.Money { return models.NewMoney(amount) }
+
+// testRules: 5% GST (18% on beverages), 20.00 packaging, 30.00 delivery for
+// the first 3 km then 8.00 per started km, 25.00 below 149.00, 5.00 platform
+func testRules() Rules {
+	return Rules{
+		GSTBps:              map[string]int64{"beverages": 1800},
+		DefaultGSTBps:       500,
+		PackagingFee:        rs(2000),
+		DeliveryBaseFee:     rs(3000),
+		DeliveryBaseKm:      3,
+		DeliveryPerKm:       rs(800),
+		SmallOrderThreshold: rs(14900),
+		SmallOrderFee:       rs(2500),
+		PlatformFee:         rs(500),
+	}
+}
+
+func TestCompute(t *testing.T) {
+	noFees := testRules()
+	noFees.PackagingFee = rs(0)
+	highPlatform := noFees
+	highPlatform.PlatformFee = rs(900)
+
+	tests := []struct {
+		name  string
+		lines []Line
+		rules map[uint]Rules
+
+		wantSubtotal, wantGST, wantPackaging, wantDelivery, wantSmall, wantPlatform, wantTotal int64
+		wantRestaurants                                                                        []uint
+	}{
+		{
+			name: "GST by category",
+			lines: []Line{
+				{ProductID: 1, Category: "food", RestaurantID: 7, UnitPrice: rs(12000), Quantity: 2},
+				{ProductID: 2, Category: "beverages", RestaurantID: 7, UnitPrice: rs(6000), Quantity: 1},
+			},
+			rules:        map[uint]Rules{7: testRules()},
+			wantSubtotal: 30000, wantGST: 1200 + 1080, wantPackaging: 2000, wantDelivery: 3000,
+			wantSmall: 0, wantPlatform: 500, wantTotal: 37780,
+			wantRestaurants: []uint{7},
+		},
+		{
+			name:         "small order fee below the threshold",
+			lines:        []Line{{ProductID: 1, RestaurantID: 7, UnitPrice: rs(9900), Quantity: 1}},
+			rules:        map[uint]Rules{7: testRules()},
+			wantSubtotal: 9900, wantGST: 495, wantPackaging: 2000, wantDelivery: 3000,
+			wantSmall: 2500, wantPlatform: 500, wantTotal: 18395,
+			wantRestaurants: []uint{7},
+		},
+		{
+			name:         "no small order fee at the threshold",
+			lines:        []Line{{ProductID: 1, RestaurantID: 7, UnitPrice: rs(14900), Quantity: 1}},
+			rules:        map[uint]Rules{7: testRules()},
+			wantSubtotal: 14900, wantGST: 745, wantPackaging: 2000, wantDelivery: 3000,
+			wantSmall: 0, wantPlatform: 500, wantTotal: 21145,
+			wantRestaurants: []uint{7},
+		},
+		{
+			name:         "GST rounds half up per line",
+			lines:        []Line{{ProductID: 1, RestaurantID: 7, UnitPrice: rs(99), Quantity: 1}},
+			rules:        map[uint]Rules{7: noFees},
+			wantSubtotal: 99, wantGST: 5, wantDelivery: 3000, wantSmall: 2500, wantPlatform: 500,
+			wantTotal:       6104,
+			wantRestaurants: []uint{7},
+		},
+		{
+			name: "fees per restaurant, one platform fee per order",
+			lines: []Line{
+				{ProductID: 1, RestaurantID: 7, UnitPrice: rs(20000), Quantity: 1},
+				{ProductID: 2, RestaurantID: 9, UnitPrice: rs(15000), Quantity: 1},
+				{ProductID: 3, RestaurantID: 7, UnitPrice: rs(5000), Quantity: 1},
+			},
+			rules:        map[uint]Rules{7: noFees, 9: highPlatform},
+			wantSubtotal: 40000, wantGST: 2000, wantDelivery: 6000, wantPlatform: 900,
+			wantTotal:       48900,
+			wantRestaurants: []uint{7, 9},
+		},
+		{
+			name:            "empty cart costs nothing",
+			rules:           map[uint]Rules{},
+			wantRestaurants: []uint{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bill := Compute(tt.lines, func(id uint) Rules { return tt.rules[id] }, nil)
+
+			for _, c := range []struct {
+				field     string
+				got, want int64
+			}{
+				{"subtotal", bill.Subtotal.Amount, tt.wantSubtotal},
+				{"gst", bill.GST.Amount, tt.wantGST},
+				{"packaging", bill.Packaging.Amount, tt.wantPackaging},
+				{"delivery", bill.Delivery.Amount, tt.wantDelivery},
+				{"small order fee", bill.SmallOrderFee.Amount, tt.wantSmall},
+				{"platform fee", bill.PlatformFee.Amount, tt.wantPlatform},
+				{"total", bill.Total.Amount, tt.wantTotal},
+			} {
+				if c.got != c.want {
+					t.Errorf("%s = %d, want %d", c.field, c.got, c.want)
+				}
+			}
+
+			if len(bill.Items) != len(tt.lines) {
+				t.Fatalf("%d items, want %d", len(bill.Items), len(tt.lines))
+			}
+			if len(bill.Restaurants) != len(tt.wantRestaurants) {
+				t.Fatalf("%d restaurants, want %d", len(bill.Restaurants), len(tt.wantRestaurants))
+			}
+			var subtotal int64
+			for i, rc := range bill.Restaurants {
+				if rc.RestaurantID != tt.wantRestaurants[i] {
+					t.Errorf("restaurant %d = %d, want %d", i, rc.RestaurantID, tt.wantRestaurants[i])
+				}
+				subtotal += rc.Subtotal.Amount
+			}
+			if subtotal != bill.Subtotal.Amount {
+				t.Errorf("restaurant subtotals add up to %d, bill says %d", subtotal, bill.Subtotal.Amount)
+			}
+		})
+	}
+}
+
+func TestComputeDistance(t *testing.T) {
+	r := testRules()
+	r.Origin = &Location{Lat: 12.9716, Lng: 77.5946}
+	dest := &Location{Lat: 13.0156, Lng: 77.5946} // 0.044° north, about 4.9 km
+
+	bill := Compute([]Line{{ProductID: 1, RestaurantID: 7, UnitPrice: rs(20000), Quantity: 1}},
+		func(uint) Rules { return r }, dest)
+	rc := bill.Restaurants[0]
+	if rc.DistanceKm == nil || math.Abs(*rc.DistanceKm-4.89) > 0.05 {
+		t.Fatalf("distance = %v, want about 4.9 km", rc.DistanceKm)
+	}
+	// 2 started km past the first 3
+	if want := int64(3000 + 2*800); rc.Delivery.Amount != want {
+		t.Errorf("delivery = %d, want %d", rc.Delivery.Amount, want)
+	}
+}
+
+func TestDeliveryFee(t *testing.T) {
+	km := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		distance *float64
+		want     int64
+	}{
+		{"unknown distance pays the base fee", nil, 3000},
+		{"inside the base distance", km(2), 3000},
+		{"exactly the base distance", km(3), 3000},
+		{"a started km counts in full", km(3.1), 3800},
+		{"several km", km(5.5), 5400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeliveryFee(testRules(), tt.distance); got.Amount != tt.want {
+				t.Errorf("DeliveryFee = %d, want %d", got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestGSTRate(t *testing.T) {
+	r := testRules()
+	r.GSTBps["packaged"] = 1200
+	tests := map[string]int64{
+		"beverages": 1800,
+		"packaged":  1200,
+		"food":      500,
+		"":          500,
+	}
+	for category, want := range tests {
+		if got := GSTRate(r, category); got != want {
+			t.Errorf("GSTRate(%q) = %d, want %d", category, got, want)
+		}
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Location
+		want float64
+	}{
+		{"same point", Location{12.97, 77.59}, Location{12.97, 77.59}, 0},
+		{"one degree of latitude", Location{0, 0}, Location{1, 0}, 111.19},
+		{"one degree of longitude at the equator", Location{0, 0}, Location{0, 1}, 111.19},
+		{"Bengaluru to Chennai", Location{12.9716, 77.5946}, Location{13.0827, 80.2707}, 290.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DistanceKm(tt.a, tt.b); math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("DistanceKm = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
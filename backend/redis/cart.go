@@ -3,39 +3,329 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"swiggy-clone/backend/models"
+
+	goredis "github.com/redis/go-redis/v9"
 )
 
 const cartTTL = 1000 * time.Minute
 
-func cartKey(userID uint) string {
-	return fmt.Sprintf("user:%d:cart", userID)
+var (
+	// ErrCartQuantityLimit means an add would take a line past the maximum quantity
+	ErrCartQuantityLimit = errors.New("cart quantity limit reached")
+	// ErrNotInCart means the product has no line in the cart
+	ErrNotInCart = errors.New("product is not in the cart")
+)
+
+// RestaurantConflictError means the cart holds items from another restaurant.
+// A cart belongs to one restaurant at a time.
+type RestaurantConflictError struct {
+	CurrentAdminID uint
+}
+
+func (e *RestaurantConflictError) Error() string {
+	return "your cart has items from another restaurant"
+}
+
+// CartOwner identifies whose cart it is: a signed-in user or a guest session.
+// Both kinds are stored the same way, only the key prefix differs.
+type CartOwner string
+
+func UserCart(userID uint) CartOwner {
+	return CartOwner(fmt.Sprintf("user:%d", userID))
 }
 
-func SetCart(ctx context.Context, userID uint, cart []models.CartItem) error {
-	data, err := json.Marshal(cart)
+func GuestCart(guestID string) CartOwner {
+	return CartOwner("guest:" + guestID)
+}
+
+func cartKey(owner CartOwner) string {
+	return string(owner) + ":cart"
+}
+
+// The cart is a hash with three fields per line, so a Lua script can change
+// one line in place instead of rewriting the whole cart:
+//
+//	<line>:item  JSON of the CartItem (quantity left out)
+//	<line>:qty   quantity
+//	<line>:pos   insertion order, so the cart lists in the order items were added
+//
+// plus a _seq counter for the positions. A line is one product today; the key
+// leaves room for variants.
+func lineKey(productID uint) string {
+	return strconv.FormatUint(uint64(productID), 10)
+}
+
+// Every script starts by converting a cart still stored the old way (one JSON
+// array in a string key) into a hash, keeping its TTL. Carts migrate lazily on
+// first touch.
+const migrateLua = `
+local function migrate(key)
+  if redis.call('TYPE', key).ok ~= 'string' then return end
+  local raw = redis.call('GET', key)
+  local ttl = redis.call('PTTL', key)
+  redis.call('DEL', key)
+  local ok, items = pcall(cjson.decode, raw)
+  if ok and type(items) == 'table' then
+    for i, it in ipairs(items) do
+      if type(it) == 'table' and it.productId and it.quantity then
+        local line = tostring(it.productId)
+        redis.call('HSET', key,
+          line .. ':item', cjson.encode({productId = it.productId, adminId = it.adminId, price = it.price}),
+          line .. ':qty', it.quantity,
+          line .. ':pos', i)
+      end
+    end
+    redis.call('HSET', key, '_seq', #items)
+  end
+  if ttl > 0 then redis.call('PEXPIRE', key, ttl) end
+end
+
+local function touch(ttl)
+  for _, k in ipairs(KEYS) do
+    if redis.call('EXISTS', k) == 1 then redis.call('PEXPIRE', k, ttl) end
+  end
+end
+`
+
+// KEYS: cart, coupon. ARGV: ttl ms
+var getCartScript = goredis.NewScript(migrateLua + `
+migrate(KEYS[1])
+touch(ARGV[1])
+return redis.call('HGETALL', KEYS[1])
+`)
+
+// KEYS: cart, coupon. ARGV: line, item JSON, delta, max (0 = none), ttl ms, restaurant (admin) ID
+var addToCartScript = goredis.NewScript(migrateLua + `
+migrate(KEYS[1])
+local line, delta, max = ARGV[1], tonumber(ARGV[3]), tonumber(ARGV[4])
+
+-- One restaurant per cart: any line from another admin is a conflict. Lines
+-- with no admin (missing, null or 0, from old carts) belong to nobody, and
+-- Lua counts 0 as true, so they are skipped explicitly.
+local fields = redis.call('HGETALL', KEYS[1])
+for i = 1, #fields, 2 do
+  if string.sub(fields[i], -5) == ':item' then
+    local ok, it = pcall(cjson.decode, fields[i + 1])
+    local admin = ok and type(it) == 'table' and tonumber(it.adminId) or 0
+    if admin ~= 0 and tostring(admin) ~= ARGV[6] then
+      return redis.error_reply('CART_RESTAURANT_CONFLICT ' .. tostring(admin))
+    end
+  end
+end
+
+local qty = tonumber(redis.call('HGET', KEYS[1], line .. ':qty') or '0') + delta
+if qty < 1 then return redis.error_reply('CART_INVALID_QUANTITY') end
+if max > 0 and qty > max then return redis.error_reply('CART_LIMIT') end
+if redis.call('HEXISTS', KEYS[1], line .. ':item') == 0 then
+  redis.call('HSET', KEYS[1], line .. ':item', ARGV[2], line .. ':pos', redis.call('HINCRBY', KEYS[1], '_seq', 1))
+end
+redis.call('HSET', KEYS[1], line .. ':qty', qty)
+touch(ARGV[5])
+return qty
+`)
+
+// KEYS: cart, coupon. ARGV: line, quantity, ttl ms
+var setCartQuantityScript = goredis.NewScript(migrateLua + `
+migrate(KEYS[1])
+if redis.call('HEXISTS', KEYS[1], ARGV[1] .. ':item') == 0 then
+  return redis.error_reply('CART_NOT_IN_CART')
+end
+redis.call('HSET', KEYS[1], ARGV[1] .. ':qty', ARGV[2])
+touch(ARGV[3])
+return tonumber(ARGV[2])
+`)
+
+// KEYS: cart, coupon. ARGV: line, ttl ms
+var removeFromCartScript = goredis.NewScript(migrateLua + `
+migrate(KEYS[1])
+local removed = redis.call('HDEL', KEYS[1], ARGV[1] .. ':item', ARGV[1] .. ':qty', ARGV[1] .. ':pos')
+touch(ARGV[2])
+return removed
+`)
+
+func cartKeys(owner CartOwner) []string {
+	return []string{cartKey(owner), cartCouponKey(owner)}
+}
+
+func ttlMillis() int64 {
+	return cartTTL.Milliseconds()
+}
+
+func cartScriptError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case strings.Contains(err.Error(), "CART_LIMIT"):
+		return ErrCartQuantityLimit
+	case strings.Contains(err.Error(), "CART_NOT_IN_CART"):
+		return ErrNotInCart
+	case strings.Contains(err.Error(), "CART_INVALID_QUANTITY"):
+		return errors.New("quantity must be at least 1")
+	case strings.Contains(err.Error(), "CART_RESTAURANT_CONFLICT"):
+		fields := strings.Fields(err.Error())
+		adminID, _ := strconv.ParseUint(fields[len(fields)-1], 10, 64)
+		return &RestaurantConflictError{CurrentAdminID: uint(adminID)}
+	}
+	return err
+}
+
+// AddToCart atomically adds delta units of item's product, creating the line
+// if needed, and returns the new quantity. max caps the line (0 for no cap).
+// Items from a different restaurant (item.AdminID) than the cart's give a
+// *RestaurantConflictError.
+func AddToCart(ctx context.Context, owner CartOwner, item models.CartItem, delta, max int) (int, error) {
+	item.Quantity = 0
+	data, err := json.Marshal(item)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return RDB.Set(ctx, cartKey(userID), data, cartTTL).Err()
+	qty, err := addToCartScript.Run(ctx, RDB, cartKeys(owner),
+		lineKey(item.ProductID), data, delta, max, ttlMillis(), item.AdminID).Int()
+	return qty, cartScriptError(err)
+}
+
+// SetCartQuantity sets the quantity of a line already in the cart
+func SetCartQuantity(ctx context.Context, owner CartOwner, productID uint, qty int) error {
+	_, err := setCartQuantityScript.Run(ctx, RDB, cartKeys(owner),
+		lineKey(productID), qty, ttlMillis()).Result()
+	return cartScriptError(err)
+}
+
+// RemoveFromCart drops a line; removing a line that isn't there is not an error
+func RemoveFromCart(ctx context.Context, owner CartOwner, productID uint) error {
+	_, err := removeFromCartScript.Run(ctx, RDB, cartKeys(owner),
+		lineKey(productID), ttlMillis()).Result()
+	return cartScriptError(err)
+}
+
+// SetCart replaces the whole cart
+func SetCart(ctx context.Context, owner CartOwner, cart []models.CartItem) error {
+	return replaceCart(ctx, owner, cart, false)
 }
 
-func GetCart(ctx context.Context, userID uint) ([]models.CartItem, error) {
-	val, err := RDB.Get(ctx, cartKey(userID)).Result()
+// ReplaceCart empties the cart, drops its coupon and stores cart instead, in
+// one MULTI so no other request sees it half-done
+func ReplaceCart(ctx context.Context, owner CartOwner, cart []models.CartItem) error {
+	return replaceCart(ctx, owner, cart, true)
+}
+
+func replaceCart(ctx context.Context, owner CartOwner, cart []models.CartItem, dropCoupon bool) error {
+	key := cartKey(owner)
+	_, err := RDB.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if dropCoupon {
+			pipe.Del(ctx, cartCouponKey(owner))
+		}
+		if len(cart) == 0 {
+			return nil
+		}
+		fields := make([]interface{}, 0, len(cart)*6+2)
+		for i, item := range cart {
+			line := lineKey(item.ProductID)
+			qty := item.Quantity
+			item.Quantity = 0
+			data, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, line+":item", data, line+":qty", qty, line+":pos", i+1)
+		}
+		fields = append(fields, "_seq", len(cart))
+		pipe.HSet(ctx, key, fields...)
+		pipe.Expire(ctx, key, cartTTL)
+		return nil
+	})
+	return err
+}
+
+// GetCart returns the cart in the order items were added and refreshes its
+// TTL. A missing cart is empty, not an error.
+func GetCart(ctx context.Context, owner CartOwner) ([]models.CartItem, error) {
+	raw, err := getCartScript.Run(ctx, RDB, cartKeys(owner), ttlMillis()).StringSlice()
 	if err != nil {
 		return nil, err
 	}
 
-	var cart []models.CartItem
-	if err := json.Unmarshal([]byte(val), &cart); err != nil {
-		return nil, err
+	type line struct {
+		item models.CartItem
+		qty  int
+		pos  int
+		ok   bool
+	}
+	lines := map[string]*line{}
+	get := func(k string) *line {
+		if lines[k] == nil {
+			lines[k] = &line{}
+		}
+		return lines[k]
+	}
+	for i := 0; i+1 < len(raw); i += 2 {
+		field, val := raw[i], raw[i+1]
+		name, kind, found := strings.Cut(field, ":")
+		if !found {
+			continue // _seq
+		}
+		l := get(name)
+		switch kind {
+		case "item":
+			if err := json.Unmarshal([]byte(val), &l.item); err == nil {
+				l.ok = true
+			}
+		case "qty":
+			l.qty, _ = strconv.Atoi(val)
+		case "pos":
+			l.pos, _ = strconv.Atoi(val)
+		}
+	}
+
+	sorted := make([]*line, 0, len(lines))
+	for _, l := range lines {
+		if l.ok && l.qty > 0 {
+			l.item.Quantity = l.qty
+			sorted = append(sorted, l)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].pos < sorted[j].pos })
+
+	cart := make([]models.CartItem, 0, len(sorted))
+	for _, l := range sorted {
+		cart = append(cart, l.item)
 	}
 	return cart, nil
 }
 
-func ClearCart(ctx context.Context, userID uint) error {
-	return RDB.Del(ctx, cartKey(userID)).Err()
+func ClearCart(ctx context.Context, owner CartOwner) error {
+	return RDB.Del(ctx, cartKey(owner), cartCouponKey(owner)).Err()
+}
+
+// The applied coupon code lives next to the cart and expires with it
+
+func cartCouponKey(owner CartOwner) string {
+	return string(owner) + ":cart:coupon"
+}
+
+func SetCartCoupon(ctx context.Context, owner CartOwner, code string) error {
+	return RDB.Set(ctx, cartCouponKey(owner), code, cartTTL).Err()
+}
+
+// GetCartCoupon returns "" when no coupon is applied
+func GetCartCoupon(ctx context.Context, owner CartOwner) (string, error) {
+	code, err := RDB.Get(ctx, cartCouponKey(owner)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", nil
+	}
+	return code, err
+}
+
+func ClearCartCoupon(ctx context.Context, owner CartOwner) error {
+	return RDB.Del(ctx, cartCouponKey(owner)).Err()
 }
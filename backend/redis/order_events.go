@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"swiggy-clone/backend/models"
+)
+
+const orderEventsChannel = "orders:events"
+
+// OrderEvent says an order changed status. It goes out over Redis pub/sub so
+// subscribers connected to any instance hear about it.
+type OrderEvent struct {
+	OrderID uint               `json:"orderId"`
+	UserID  uint               `json:"userId"`
+	Admins  []string           `json:"admins"` // Order.ProductAdmins
+	Status  models.OrderStatus `json:"status"`
+	At      time.Time          `json:"at"`
+}
+
+func PublishOrderEvent(ctx context.Context, ev OrderEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return RDB.Publish(ctx, orderEventsChannel, data).Err()
+}
+
+// SubscribeOrderEvents delivers every order event until ctx is done. The
+// connection reconnects on its own; events published while it is down are lost.
+func SubscribeOrderEvents(ctx context.Context) <-chan OrderEvent {
+	sub := RDB.Subscribe(ctx, orderEventsChannel)
+	out := make(chan OrderEvent, 64)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		msgs := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var ev OrderEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+					log.Printf("order events: bad payload: %v", err)
+					continue
+				}
+				out <- ev
+			}
+		}
+	}()
+	return out
+}
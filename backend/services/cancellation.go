@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/models"
+)
+
+// ErrTooLateToCancel means the food is already on its way
+var ErrTooLateToCancel = errors.New("order can no longer be cancelled once it has been picked up")
+
+// RefundPolicy decides how much of what was paid a customer gets back when
+// they cancel, by how far the order had got
+type RefundPolicy struct {
+	// Refunded while the restaurant has accepted or is preparing the order
+	PreparingRefundBps int64
+}
+
+// RefundBps returns the share of the payment to refund, in basis points, for
+// a customer cancelling an order in the given status
+func (p RefundPolicy) RefundBps(status models.OrderStatus) (int64, error) {
+	switch status {
+	case models.OrderPlaced:
+		return 10000, nil
+	case models.OrderAccepted, models.OrderPreparing:
+		return p.PreparingRefundBps, nil
+	case models.OrderReady:
+		return 0, nil
+	case models.OrderPickedUp, models.OrderDelivered:
+		return 0, ErrTooLateToCancel
+	}
+	return 0, &InvalidTransitionError{From: status, To: models.OrderCancelled}
+}
+
+// Cancel cancels a customer's order under the refund policy: stock goes back
+// on the shelf, the coupon can be used again and the refund is spread over
+// the order's payments
+func (s *OrderService) Cancel(ctx context.Context, orderID, userID uint, reason string) (*models.Order, error) {
+	var order *models.Order
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locked up front so a restaurant accepting at the same moment can't
+		// change the status between the policy check and the cancellation
+		var current models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "user_id", "status").First(&current, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		if current.UserID != userID {
+			return ErrOrderNotFound
+		}
+		bps, err := s.Refunds.RefundBps(current.Status)
+		if err != nil {
+			return err
+		}
+
+		order, err = s.TransitionTx(tx, orderID, models.OrderCancelled, UserActor(userID), reason)
+		if err != nil {
+			return err
+		}
+		if err := s.Inventory.ReturnOrderStock(tx, orderID, "order cancelled: "+reason); err != nil {
+			return err
+		}
+		if err := s.Coupons.Unredeem(tx, orderID); err != nil {
+			return err
+		}
+		return s.refund(tx, order, bps, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Publish(ctx, order)
+	return order, nil
+}
+
+// refund pays back bps of what was paid for the order, split over its
+// payments in proportion to their amounts, and records the outcome on the order
+func (s *OrderService) refund(tx *gorm.DB, order *models.Order, bps int64, reason string) error {
+	var payments []models.Payment
+	if err := tx.Where("order_id = ? AND status = ?", fmt.Sprint(order.ID), models.PaymentSuccess).
+		Order("id").
+		Find(&payments).Error; err != nil {
+		return err
+	}
+
+	paid := models.NewMoney(0)
+	weights := make([]int64, len(payments))
+	for i, p := range payments {
+		paid = paid.Add(p.Amount)
+		weights[i] = p.Amount.Amount
+	}
+	total := paid.Percent(bps)
+
+	if total.Amount > 0 {
+		for i, amount := range total.Allocate(weights) {
+			status := models.PaymentPartiallyRefunded
+			if amount.Amount == payments[i].Amount.Amount {
+				status = models.PaymentRefunded
+			}
+			if err := tx.Model(&payments[i]).Updates(map[string]interface{}{
+				"refunded_amount": amount,
+				"status":          status,
+			}).Error; err != nil {
+				return fmt.Errorf("refund payment %d: %w", payments[i].ID, err)
+			}
+		}
+	}
+
+	order.CancelReason, order.RefundAmount = &reason, total
+	return tx.Model(order).Updates(map[string]interface{}{
+		"cancel_reason": reason,
+		"refund_amount": total,
+	}).Error
+}
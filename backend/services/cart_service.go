@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+)
+
+// How a guest cart is folded into the user's cart on login/signup
+const (
+	MergeSum   = "sum"   // add quantities of lines in both carts, capped at the max
+	MergeMax   = "max"   // keep the larger quantity of lines in both carts
+	MergeGuest = "guest" // the guest cart replaces the user's
+	MergeUser  = "user"  // keep the user's cart; the guest cart is used only if it is empty
+)
+
+// CartService handles carts that span more than one owner
+type CartService struct {
+	MergeStrategy string
+	MaxQuantity   int
+}
+
+// MergeGuestCart moves the guest's cart into the user's cart and deletes it.
+// A cart holds one restaurant, so when the two carts are from different
+// restaurants under MergeSum or MergeMax, both are left as they are and a
+// *redis.RestaurantConflictError is returned.
+func (s *CartService) MergeGuestCart(ctx context.Context, guestID string, userID uint) error {
+	guest, user := redis.GuestCart(guestID), redis.UserCart(userID)
+
+	guestItems, err := redis.GetCart(ctx, guest)
+	if err != nil {
+		return fmt.Errorf("load guest cart: %w", err)
+	}
+	if len(guestItems) == 0 {
+		return redis.ClearCart(ctx, guest)
+	}
+	userItems, err := redis.GetCart(ctx, user)
+	if err != nil {
+		return fmt.Errorf("load user cart: %w", err)
+	}
+
+	merged, fromGuest, err := mergeCarts(userItems, guestItems, s.MergeStrategy, s.MaxQuantity)
+	if err != nil {
+		return err
+	}
+	if err := redis.SetCart(ctx, user, merged); err != nil {
+		return fmt.Errorf("save merged cart: %w", err)
+	}
+
+	// The guest's coupon follows their items, unless the user already has one
+	if fromGuest {
+		code, _ := redis.GetCartCoupon(ctx, guest)
+		current, _ := redis.GetCartCoupon(ctx, user)
+		if code != "" && current == "" {
+			if err := redis.SetCartCoupon(ctx, user, code); err != nil {
+				return err
+			}
+		}
+	}
+	return redis.ClearCart(ctx, guest)
+}
+
+// mergeCarts returns the merged lines and whether any of them came from the
+// guest cart. User lines keep their order; new guest lines go after them.
+// Lines from two restaurants can't be combined, so that is a conflict.
+func mergeCarts(user, guest []models.CartItem, strategy string, max int) ([]models.CartItem, bool, error) {
+	if len(user) == 0 {
+		return capQuantities(guest, max), true, nil
+	}
+	switch strategy {
+	case MergeUser:
+		return user, false, nil
+	case MergeGuest:
+		return capQuantities(guest, max), true, nil
+	}
+	// Lines without a restaurant (from before carts recorded one) don't
+	// conflict with anything, as in the add-to-cart script
+	if u, g := cartRestaurant(user), cartRestaurant(guest); u != 0 && g != 0 && u != g {
+		return nil, false, &redis.RestaurantConflictError{CurrentAdminID: u}
+	}
+
+	merged := make([]models.CartItem, len(user))
+	copy(merged, user)
+	index := map[uint]int{}
+	for i, item := range merged {
+		index[item.ProductID] = i
+	}
+	for _, item := range guest {
+		i, ok := index[item.ProductID]
+		if !ok {
+			index[item.ProductID] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+		if strategy == MergeMax {
+			if item.Quantity > merged[i].Quantity {
+				merged[i].Quantity = item.Quantity
+			}
+		} else {
+			merged[i].Quantity += item.Quantity
+		}
+	}
+	return capQuantities(merged, max), true, nil
+}
+
+// cartRestaurant is the restaurant of the first line that has one, or 0
+func cartRestaurant(items []models.CartItem) uint {
+	for _, item := range items {
+		if item.AdminID != 0 {
+			return item.AdminID
+		}
+	}
+	return 0
+}
+
+func capQuantities(items []models.CartItem, max int) []models.CartItem {
+	if max <= 0 {
+		return items
+	}
+	for i := range items {
+		if items[i].Quantity > max {
+			items[i].Quantity = max
+		}
+	}
+	return items
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+)
+
+func TestMergeCarts(t *testing.T) {
+	line := func(pid, admin uint, qty int) models.CartItem {
+		return models.CartItem{ProductID: pid, AdminID: admin, Quantity: qty}
+	}
+	tests := []struct {
+		name          string
+		user, guest   []models.CartItem
+		strategy      string
+		want          []models.CartItem
+		wantFromGuest bool
+		wantConflict  uint // CurrentAdminID of the expected conflict, 0 for none
+	}{
+		{
+			name:          "empty user cart takes the guest's",
+			guest:         []models.CartItem{line(1, 7, 2)},
+			strategy:      MergeSum,
+			want:          []models.CartItem{line(1, 7, 2)},
+			wantFromGuest: true,
+		},
+		{
+			name:          "sum adds up shared lines and caps them",
+			user:          []models.CartItem{line(1, 7, 2), line(2, 7, 1)},
+			guest:         []models.CartItem{line(1, 7, 9), line(3, 7, 1)},
+			strategy:      MergeSum,
+			want:          []models.CartItem{line(1, 7, 10), line(2, 7, 1), line(3, 7, 1)},
+			wantFromGuest: true,
+		},
+		{
+			name:          "max keeps the larger quantity",
+			user:          []models.CartItem{line(1, 7, 2)},
+			guest:         []models.CartItem{line(1, 7, 5)},
+			strategy:      MergeMax,
+			want:          []models.CartItem{line(1, 7, 5)},
+			wantFromGuest: true,
+		},
+		{
+			name:     "user strategy keeps the user's cart",
+			user:     []models.CartItem{line(1, 7, 2)},
+			guest:    []models.CartItem{line(2, 9, 1)},
+			strategy: MergeUser,
+			want:     []models.CartItem{line(1, 7, 2)},
+		},
+		{
+			name:          "guest strategy replaces the user's cart",
+			user:          []models.CartItem{line(1, 7, 2)},
+			guest:         []models.CartItem{line(2, 9, 1)},
+			strategy:      MergeGuest,
+			want:          []models.CartItem{line(2, 9, 1)},
+			wantFromGuest: true,
+		},
+		{
+			name:         "two restaurants conflict",
+			user:         []models.CartItem{line(1, 7, 2)},
+			guest:        []models.CartItem{line(2, 9, 1)},
+			strategy:     MergeSum,
+			wantConflict: 7,
+		},
+		{
+			name:         "lines without a restaurant are skipped when looking for one",
+			user:         []models.CartItem{line(1, 0, 1), line(2, 7, 1)},
+			guest:        []models.CartItem{line(3, 0, 1), line(4, 9, 1)},
+			strategy:     MergeSum,
+			wantConflict: 7,
+		},
+		{
+			name:          "a legacy line without a restaurant is no conflict",
+			user:          []models.CartItem{line(1, 0, 1), line(2, 7, 1)},
+			guest:         []models.CartItem{line(3, 7, 1)},
+			strategy:      MergeSum,
+			want:          []models.CartItem{line(1, 0, 1), line(2, 7, 1), line(3, 7, 1)},
+			wantFromGuest: true,
+		},
+		{
+			name:          "a cart with no restaurant at all merges",
+			user:          []models.CartItem{line(1, 0, 1)},
+			guest:         []models.CartItem{line(2, 9, 1)},
+			strategy:      MergeMax,
+			want:          []models.CartItem{line(1, 0, 1), line(2, 9, 1)},
+			wantFromGuest: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fromGuest, err := mergeCarts(tt.user, tt.guest, tt.strategy, 10)
+			if tt.wantConflict != 0 {
+				var conflict *redis.RestaurantConflictError
+				if !errors.As(err, &conflict) || conflict.CurrentAdminID != tt.wantConflict {
+					t.Fatalf("merge = %v, want a conflict with restaurant %d", err, tt.wantConflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("merge: %v", err)
+			}
+			if fromGuest != tt.wantFromGuest {
+				t.Errorf("fromGuest = %v, want %v", fromGuest, tt.wantFromGuest)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merged %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,142 +2,330 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"time"
 
-	"swiggy-clone/backend/kafka"
+	"swiggy-clone/backend/events"
+	"swiggy-clone/backend/idempotency"
 	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/pricing"
 	"swiggy-clone/backend/redis"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrEmptyCart        = errors.New("cart is empty")
+	ErrRestaurantClosed = errors.New("restaurant is closed")
+	// ErrMixedRestaurants guards carts filled before carts were limited to one restaurant
+	ErrMixedRestaurants = errors.New("cart has items from more than one restaurant; remove some to continue")
+)
+
 // CheckoutService handles all logic related to order placement
 type CheckoutService struct {
-	DB    *gorm.DB
-	Redis redis.Client
-	Queue kafka.OrderQueue
+	DB          *gorm.DB
+	Events      *events.Bus
+	Inventory   *InventoryService
+	Pricing     *PricingService
+	Coupons     *CouponService
+	Orders      *OrderService
+	Idempotency *idempotency.Store
 }
 
-// CartItemFromRedis matches the structure stored in Redis
-type CartItemFromRedis struct {
-	ProductID uint `json:"productId"`
-	Quantity  int  `json:"quantity"`
-}
+// Checkout does everything needed to place an order: it locks the products,
+// takes the stock, snapshots what was bought, clears the cart and leaves an
+// order.placed event in the outbox. Repeating a call with the same idempotency key returns
+// the order created the first time.
+func (s *CheckoutService) Checkout(ctx context.Context, userID uint, idempotencyKey *string) (*models.Order, error) {
+	cartItems, couponCode, err := s.loadCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if idempotencyKey == nil || *idempotencyKey == "" {
+		return s.placeOrder(ctx, userID, nil, cartItems, couponCode)
+	}
+	if s.Idempotency == nil {
+		return s.placeOrder(ctx, userID, idempotencyKey, cartItems, couponCode)
+	}
 
-// CartFromRedis represents the full cart object stored in Redis
-type CartFromRedis struct {
-	Items []CartItemFromRedis `json:"items"`
+	// A successful checkout empties the cart, so a retry after it finds
+	// nothing to fingerprint; the order the key made is the answer
+	if len(cartItems) == 0 {
+		if existing, err := s.findByIdempotencyKey(ctx, userID, *idempotencyKey); err == nil {
+			return existing, nil
+		}
+	}
+
+	// The key is claimed in Redis before the order is placed, so concurrent
+	// retries wait for (or replay) the first attempt instead of racing it.
+	// It is bound to the cart and coupon: reusing it for a different cart is
+	// refused rather than answered with the old order.
+	orderID, err := idempotency.Do(ctx, s.Idempotency, "checkout", userID, *idempotencyKey,
+		cartFingerprint(cartItems, couponCode),
+		func() (uint, error) {
+			order, err := s.placeOrder(ctx, userID, idempotencyKey, cartItems, couponCode)
+			if err != nil {
+				return 0, err
+			}
+			return order.ID, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// Always return the current state of the order, not a stale copy
+	var order models.Order
+	if err := s.DB.WithContext(ctx).Preload("Items").First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
 }
 
-// Checkout does everything needed to place an order
-func (s *CheckoutService) Checkout(ctx context.Context, userID uint, idempotencyKey *string) (*models.Order, error) {
-	// 1. Get cart
-	var cartItems []CartItemFromRedis
-	found, err := s.Redis.GetJSON(ctx, fmt.Sprintf("user:%d:cart", userID), &cartItems)
+// loadCart reads the user's cart and the coupon applied to it. A missing
+// cart reads as empty, so an error means Redis itself failed.
+func (s *CheckoutService) loadCart(ctx context.Context, userID uint) ([]models.CartItem, string, error) {
+	cartItems, err := redis.GetCart(ctx, redis.UserCart(userID))
 	if err != nil {
-		return nil, fmt.Errorf("redis error: %w", err)
-	}
-	if !found || len(cartItems) == 0 {
-		return nil, fmt.Errorf("cart is empty")
-	}
-
-	// 2. Begin DB transaction
-	var order *models.Order
-	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Map productId → quantity
-		qtyMap := make(map[uint]int)
-		ids := []uint{}
-		for _, item := range cartItems {
-			qtyMap[item.ProductID] = item.Quantity
-			ids = append(ids, item.ProductID)
-		}
-
-		// 3. Fetch product rows with FOR UPDATE (lock for stock check)
-		type productData struct {
-			ID    uint
-			Price float64
-			Stock int
-		}
-		var products []productData
-		if err := tx.
-			Table("products").
-			Where("id IN ?", ids).
-			Select("id, price, stock").
-			Clauses(clause.Locking{Strength: "UPDATE"}).
-			Scan(&products).Error; err != nil {
-			return err
+		return nil, "", fmt.Errorf("read cart: %w", err)
+	}
+	// Without the coupon the order would be charged full price, so a failed
+	// read fails the checkout
+	couponCode, err := redis.GetCartCoupon(ctx, redis.UserCart(userID))
+	if err != nil {
+		return nil, "", fmt.Errorf("read cart coupon: %w", err)
+	}
+	return cartItems, couponCode, nil
+}
+
+// cartFingerprint identifies what a checkout buys, for its idempotency key
+func cartFingerprint(items []models.CartItem, couponCode string) string {
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, fmt.Sprintf("%d:%d", item.ProductID, item.Quantity))
+	}
+	sort.Strings(lines)
+	return idempotency.Fingerprint("checkout", lines, couponCode)
+}
+
+func (s *CheckoutService) placeOrder(ctx context.Context, userID uint, idempotencyKey *string, cartItems []models.CartItem, couponCode string) (*models.Order, error) {
+	// 1. Same key, same order (covers replays after the Redis record expired)
+	if idempotencyKey != nil {
+		if existing, err := s.findByIdempotencyKey(ctx, userID, *idempotencyKey); err == nil {
+			return existing, nil
 		}
+	}
 
-		// 4. Validate stock & calculate total
-		var total float64
-		orderItems := make([]models.OrderItem, 0, len(products))
+	// 2. The cart, read by Checkout
+	if len(cartItems) == 0 {
+		return nil, ErrEmptyCart
+	}
 
-		for _, p := range products {
-			qty := qtyMap[p.ID]
-			if qty <= 0 || qty > p.Stock {
-				return fmt.Errorf("invalid or insufficient stock for product %d", p.ID)
-			}
-			total += float64(qty) * p.Price
+	lines := make([]StockLine, 0, len(cartItems))
+	for _, item := range cartItems {
+		lines = append(lines, StockLine{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
 
-			orderItems = append(orderItems, models.OrderItem{
-				ProductID:       p.ID,
-				Quantity:        qty,
-				PriceAtPurchase: p.Price,
-			})
+	// 3. Begin DB transaction
+	var (
+		order   *models.Order
+		soldOut bool
+	)
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// One checkout per user at a time, so a first-order-only coupon sees
+		// every other order of theirs (see CouponService.Redeem)
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return fmt.Errorf("lock user: %w", err)
 		}
 
-		// 5. Create order
+		// 4. Create order (details are filled in once products are locked)
 		order = &models.Order{
 			UserID:         userID,
-			Total:          roundToTwo(total),
-			Status:         models.OrderPending,
+			Products:       datatypes.JSON("[]"),
+			Status:         models.OrderPlaced,
 			PlacedAt:       time.Now(),
 			IdempotencyKey: idempotencyKey,
 		}
 		if err := tx.Create(order).Error; err != nil {
 			return fmt.Errorf("order create failed: %w", err)
 		}
-
-		// 6. Add order_id to each item and insert
-		for i := range orderItems {
-			orderItems[i].OrderID = order.ID
+		if err := s.Orders.Placed(tx, order, UserActor(userID)); err != nil {
+			return err
 		}
-		if err := tx.Create(&orderItems).Error; err != nil {
+
+		// 5. Lock products, take stock and hold it until payment
+		products, err := s.Inventory.Reserve(tx, userID, order.ID, lines)
+		if err != nil {
 			return err
 		}
 
-		// 7. Decrement product stock
+		// Closed restaurants can't take orders (the rollback returns the stock)
+		adminIDs := make([]uint, 0, len(products))
 		for _, p := range products {
-			qty := qtyMap[p.ID]
-			if err := tx.Model(&models.Product{}).
-				Where("id = ? AND stock >= ?", p.ID, qty).
-				UpdateColumn("stock", gorm.Expr("stock - ?", qty)).Error; err != nil {
-				return fmt.Errorf("stock update failed: %w", err)
+			adminIDs = append(adminIDs, p.AdminID)
+		}
+		for _, id := range adminIDs {
+			if id != adminIDs[0] {
+				return ErrMixedRestaurants
+			}
+		}
+		closed, err := s.Pricing.ClosedRestaurants(tx, adminIDs)
+		if err != nil {
+			return err
+		}
+		for _, p := range products {
+			if closed[p.AdminID] {
+				return fmt.Errorf("%s: %w", p.Name, ErrRestaurantClosed)
+			}
+		}
+
+		// 6. Build items + snapshots from the locked rows
+		adminSet := map[uint]bool{}
+		orderItems := make([]models.OrderItem, 0, len(lines))
+		snapshots := make([]models.ProductSnapshot, 0, len(lines))
+		billLines := make([]pricing.Line, 0, len(lines))
+		for _, line := range lines {
+			p := products[line.ProductID]
+			billLines = append(billLines, pricing.Line{
+				ProductID:    p.ID,
+				Name:         p.Name,
+				Category:     p.Category,
+				RestaurantID: p.AdminID,
+				UnitPrice:    p.Price,
+				Quantity:     line.Quantity,
+			})
+			if !adminSet[p.AdminID] {
+				adminSet[p.AdminID] = true
+				order.ProductAdmins = append(order.ProductAdmins, fmt.Sprint(p.AdminID))
+			}
+			if p.Stock == 0 {
+				soldOut = true
+			}
+
+			orderItems = append(orderItems, models.OrderItem{
+				OrderID:         order.ID,
+				ProductID:       p.ID,
+				Quantity:        line.Quantity,
+				PriceAtPurchase: p.Price,
+			})
+			snapshots = append(snapshots, models.ProductSnapshot{
+				ID:        p.ID,
+				Name:      p.Name,
+				Price:     p.Price,
+				Stock:     p.Stock,
+				AdminID:   p.AdminID,
+				Category:  p.Category,
+				Image:     p.Image,
+				Quantity:  line.Quantity,
+				CreatedAt: p.CreatedAt,
+				UpdatedAt: p.UpdatedAt,
+			})
+		}
+
+		productsJSON, err := json.Marshal(snapshots)
+		if err != nil {
+			return fmt.Errorf("failed to marshal products: %w", err)
+		}
+		order.Products = datatypes.JSON(productsJSON)
+
+		// 7. Price the order (taxes and fees) and keep the bill with it
+		bill, err := s.Pricing.Quote(tx, userID, billLines)
+		if err != nil {
+			return err
+		}
+		if couponCode != "" {
+			// A coupon that stopped applying fails the checkout rather than
+			// silently charging the full price
+			coupon, discount, err := s.Coupons.Evaluate(tx, userID, couponCode, bill, order.ID)
+			if err != nil {
+				return fmt.Errorf("coupon %s: %w", couponCode, err)
 			}
+			if err := s.Coupons.Redeem(tx, coupon, userID, order.ID, discount); err != nil {
+				return fmt.Errorf("coupon %s: %w", couponCode, err)
+			}
+			bill = pricing.WithDiscount(bill, coupon.Code, discount)
+		}
+		billJSON, err := json.Marshal(bill)
+		if err != nil {
+			return fmt.Errorf("failed to marshal bill: %w", err)
+		}
+		order.Bill = datatypes.JSON(billJSON)
+		order.Total = bill.Total
+
+		// 8. Insert items and save the order details
+		if err := tx.Create(&orderItems).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(order).Updates(map[string]interface{}{
+			"products":       order.Products,
+			"product_admins": pq.StringArray(order.ProductAdmins),
+			"total":          order.Total,
+			"bill":           order.Bill,
+		}).Error; err != nil {
+			return err
 		}
 
 		// Attach items back to order struct
 		order.Items = orderItems
-		return nil
+
+		// Subscribers hear about the order only if it commits
+		return s.Events.Publish(tx, events.OrderPlaced{
+			OrderID: order.ID,
+			UserID:  userID,
+			Admins:  order.ProductAdmins,
+			Total:   order.Total,
+		})
 	})
 	if err != nil {
+		// Lost a race with another request using the same key: the unique
+		// index on (user_id, idempotency_key) stopped a duplicate order
+		if idempotencyKey != nil && isUniqueViolation(err) {
+			return s.findByIdempotencyKey(ctx, userID, *idempotencyKey)
+		}
 		return nil, err
 	}
 
-	// 8. Clear cart in Redis
-	_ = s.Redis.Del(ctx, fmt.Sprintf("user:%d:cart", userID))
+	// 9. Clear cart in Redis (best-effort; the order is already placed)
+	if err := redis.ClearCart(ctx, redis.UserCart(userID)); err != nil {
+		log.Printf("checkout: failed to clear cart for user %d: %v", userID, err)
+	}
 
-	// 9. (Next step) Send to async worker/queue
-	if s.Queue != nil {
-		go s.Queue.Publish(ctx, order.ID)
+	// Sold-out products flip to unavailable; drop cached listings so they show it
+	if soldOut {
+		redis.DelPattern(ctx, "products:*")
 	}
 
-	// e.g., s.Queue.Publish(order.ID)
+	// Live subscribers (the restaurant's newOrders feed) hear about it now
+	s.Orders.Publish(context.WithoutCancel(ctx), order)
 
+	// 10. The outbox relay hands the order to the async worker/queue
+	s.Events.Wake()
+
+	log.Printf("checkout: created order id=%v total=%s items=%d", order.ID, order.Total, len(order.Items))
 	return order, nil
 }
-func roundToTwo(val float64) float64 {
-	return float64(int(val*100+0.5)) / 100
+
+func (s *CheckoutService) findByIdempotencyKey(ctx context.Context, userID uint, key string) (*models.Order, error) {
+	var existing models.Order
+	err := s.DB.WithContext(ctx).
+		Preload("Items").
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		First(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// isUniqueViolation reports whether a Postgres unique constraint rejected a write
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
@@ -0,0 +1,204 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"swiggy-clone/backend/db"
+	"swiggy-clone/backend/idempotency"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+	"swiggy-clone/backend/services"
+)
+
+// newCheckout wires a CheckoutService to the Postgres and Redis named by
+// TEST_DATABASE_URL and TEST_REDIS_URL, and skips the test without them.
+// The schema is migrated the way the server does it; every test makes its
+// own users and products, so runs don't interfere.
+func newCheckout(t *testing.T) *services.CheckoutService {
+	t.Helper()
+	dbURL, redisURL := os.Getenv("TEST_DATABASE_URL"), os.Getenv("TEST_REDIS_URL")
+	if dbURL == "" || redisURL == "" {
+		t.Skip("set TEST_DATABASE_URL and TEST_REDIS_URL to run checkout against Postgres and Redis")
+	}
+	gdb := db.Open(dbURL)
+	db.AutoMigrate(gdb)
+	redis.InitRedis(redisURL)
+
+	orders := &services.OrderService{DB: gdb}
+	inventory := &services.InventoryService{DB: gdb, ReservationTTL: 15 * time.Minute, Orders: orders}
+	orders.Inventory = inventory
+	coupons := &services.CouponService{DB: gdb}
+	orders.Coupons = coupons
+	return &services.CheckoutService{
+		DB:          gdb,
+		Inventory:   inventory,
+		Pricing:     &services.PricingService{DB: gdb},
+		Coupons:     coupons,
+		Orders:      orders,
+		Idempotency: idempotency.NewStore(redis.RDB, time.Hour),
+	}
+}
+
+// seed creates an admin with one product holding stock, restocked through
+// the ledger like a real delivery
+func seed(t *testing.T, s *services.CheckoutService, stock int) (admin models.User, product models.Product) {
+	t.Helper()
+	admin = newUser(t, s.DB, "admin")
+	product = models.Product{Name: "Masala Dosa", Price: models.NewMoney(12000), AdminID: admin.ID}
+	if err := s.DB.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if _, err := s.Inventory.Restock(context.Background(), admin.ID, product.ID, stock, "test stock"); err != nil {
+		t.Fatalf("restock: %v", err)
+	}
+	return admin, product
+}
+
+func newUser(t *testing.T, gdb *gorm.DB, role string) models.User {
+	t.Helper()
+	u := models.User{Email: fmt.Sprintf("%s-%d@checkout.test", role, time.Now().UnixNano()), Name: role, Role: role}
+	if err := gdb.Create(&u).Error; err != nil {
+		t.Fatalf("create %s: %v", role, err)
+	}
+	return u
+}
+
+func fillCart(t *testing.T, userID uint, product models.Product, qty int) {
+	t.Helper()
+	owner := redis.UserCart(userID)
+	if err := redis.SetCart(context.Background(), owner, []models.CartItem{
+		{ProductID: product.ID, AdminID: product.AdminID, Quantity: qty, Price: product.Price},
+	}); err != nil {
+		t.Fatalf("fill cart: %v", err)
+	}
+	t.Cleanup(func() { _ = redis.ClearCart(context.Background(), owner) })
+}
+
+func TestCheckoutConcurrentOversell(t *testing.T) {
+	s := newCheckout(t)
+	ctx := context.Background()
+
+	const stock, shoppers = 3, 10
+	_, product := seed(t, s, stock)
+
+	users := make([]models.User, shoppers)
+	for i := range users {
+		users[i] = newUser(t, s.DB, "user")
+		fillCart(t, users[i].ID, product, 1)
+	}
+
+	start := make(chan struct{})
+	errs := make([]error, shoppers)
+	var wg sync.WaitGroup
+	for i, u := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = s.Checkout(ctx, u.ID, nil)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	placed := 0
+	for i, err := range errs {
+		var short *services.InsufficientStockError
+		switch {
+		case err == nil:
+			placed++
+		case errors.As(err, &short):
+			if short.ProductID != product.ID {
+				t.Errorf("shopper %d ran short of product %d, want %d", i, short.ProductID, product.ID)
+			}
+		default:
+			t.Errorf("shopper %d: %v, want success or insufficient stock", i, err)
+		}
+	}
+	if placed != stock {
+		t.Errorf("%d orders placed, want exactly %d", placed, stock)
+	}
+
+	var after models.Product
+	if err := s.DB.First(&after, product.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if after.Stock != 0 {
+		t.Errorf("stock ended at %d, want 0", after.Stock)
+	}
+	if after.IsAvailable {
+		t.Error("product still available with no stock")
+	}
+
+	var taken int64
+	if err := s.DB.Model(&models.InventoryMovement{}).
+		Where("product_id = ? AND reason = ?", product.ID, models.MovementOrder).
+		Select("COALESCE(SUM(delta), 0)").Scan(&taken).Error; err != nil {
+		t.Fatal(err)
+	}
+	if taken != -stock {
+		t.Errorf("order movements sum to %d, want %d", taken, -stock)
+	}
+	var ledger int64
+	if err := s.DB.Model(&models.InventoryMovement{}).
+		Where("product_id = ?", product.ID).
+		Select("COALESCE(SUM(delta), 0)").Scan(&ledger).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ledger != int64(after.Stock) {
+		t.Errorf("ledger sums to %d, stock is %d", ledger, after.Stock)
+	}
+}
+
+func TestCheckoutIdempotencyKeyReplay(t *testing.T) {
+	s := newCheckout(t)
+	ctx := context.Background()
+
+	_, product := seed(t, s, 10)
+	user := newUser(t, s.DB, "user")
+	key := fmt.Sprintf("checkout-%d", time.Now().UnixNano())
+
+	fillCart(t, user.ID, product, 2)
+	first, err := s.Checkout(ctx, user.ID, &key)
+	if err != nil {
+		t.Fatalf("first checkout: %v", err)
+	}
+
+	// A retry after the cart was cleared, and one with the same cart again
+	// (the client never saw the response and rebuilt it), both get the order
+	replay, err := s.Checkout(ctx, user.ID, &key)
+	if err != nil {
+		t.Fatalf("replay with an empty cart: %v", err)
+	}
+	fillCart(t, user.ID, product, 2)
+	again, err := s.Checkout(ctx, user.ID, &key)
+	if err != nil {
+		t.Fatalf("replay with the same cart: %v", err)
+	}
+	if replay.ID != first.ID || again.ID != first.ID {
+		t.Errorf("replays returned orders %d and %d, want %d", replay.ID, again.ID, first.ID)
+	}
+
+	var orders int64
+	if err := s.DB.Model(&models.Order{}).Where("user_id = ?", user.ID).Count(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if orders != 1 {
+		t.Errorf("%d orders for the key, want 1", orders)
+	}
+	var after models.Product
+	if err := s.DB.First(&after, product.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if after.Stock != 8 {
+		t.Errorf("stock %d after one order of 2, want 8", after.Stock)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/pricing"
+)
+
+var (
+	ErrCouponNotFound  = errors.New("coupon not found")
+	ErrCouponInactive  = errors.New("coupon is no longer active")
+	ErrCouponNotYet    = errors.New("coupon is not valid yet")
+	ErrCouponExpired   = errors.New("coupon has expired")
+	ErrCouponExhausted = errors.New("coupon has been fully redeemed")
+	ErrCouponUserLimit = errors.New("you have already used this coupon the maximum number of times")
+	ErrCouponFirstOnly = errors.New("coupon is only valid on your first order")
+)
+
+// CouponService validates promo codes against a bill and records redemptions
+type CouponService struct {
+	DB *gorm.DB
+}
+
+// NormalizeCode is how codes are stored and looked up
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Create saves a new coupon
+func (s *CouponService) Create(ctx context.Context, c *models.Coupon) error {
+	c.Code = NormalizeCode(c.Code)
+	if c.Code == "" {
+		return errors.New("coupon code is required")
+	}
+	switch c.Type {
+	case models.CouponPercentage:
+		if c.PercentBps <= 0 || c.PercentBps > 10000 {
+			return errors.New("percentage must be between 0 and 100")
+		}
+	case models.CouponFlat:
+		if c.FlatAmount.Amount <= 0 {
+			return errors.New("flat amount must be greater than zero")
+		}
+	case models.CouponFreeDelivery:
+	default:
+		return fmt.Errorf("unknown coupon type %q", c.Type)
+	}
+	if c.MinOrder.IsNegative() || c.MaxDiscount.IsNegative() || c.UsageLimit < 0 || c.PerUserLimit < 0 {
+		return errors.New("limits cannot be negative")
+	}
+	if c.ValidFrom != nil && c.ValidUntil != nil && !c.ValidUntil.After(*c.ValidFrom) {
+		return errors.New("validUntil must be after validFrom")
+	}
+
+	if err := s.DB.WithContext(ctx).Create(c).Error; err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("coupon code %s already exists", c.Code)
+		}
+		return err
+	}
+	return nil
+}
+
+// SetActive switches a coupon on or off. Only its creator may do this.
+func (s *CouponService) SetActive(ctx context.Context, adminID, couponID uint, active bool) (*models.Coupon, error) {
+	var c models.Coupon
+	if err := s.DB.WithContext(ctx).First(&c, couponID).Error; err != nil {
+		return nil, ErrCouponNotFound
+	}
+	if c.CreatedBy != adminID {
+		return nil, errors.New("forbidden: coupon belongs to another admin")
+	}
+	if err := s.DB.WithContext(ctx).Model(&c).Update("active", active).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ByCreator lists the coupons an admin created, newest first
+func (s *CouponService) ByCreator(ctx context.Context, adminID uint) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := s.DB.WithContext(ctx).Where("created_by = ?", adminID).Order("id DESC").Find(&coupons).Error
+	return coupons, err
+}
+
+// Evaluate checks every rule for code and returns the coupon and the discount
+// it gives on bill. excludeOrderID is the order being placed (so it doesn't
+// count against first-order-only); pass 0 when pricing a cart.
+func (s *CouponService) Evaluate(db *gorm.DB, userID uint, code string, bill pricing.Bill, excludeOrderID uint) (*models.Coupon, models.Money, error) {
+	zero := models.NewMoney(0)
+
+	var c models.Coupon
+	if err := db.Where("code = ?", NormalizeCode(code)).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, zero, ErrCouponNotFound
+		}
+		return nil, zero, err
+	}
+
+	now := time.Now()
+	switch {
+	case !c.Active:
+		return nil, zero, ErrCouponInactive
+	case c.ValidFrom != nil && now.Before(*c.ValidFrom):
+		return nil, zero, ErrCouponNotYet
+	case c.ValidUntil != nil && !now.Before(*c.ValidUntil):
+		return nil, zero, ErrCouponExpired
+	case c.UsageLimit > 0 && c.UsedCount >= c.UsageLimit:
+		return nil, zero, ErrCouponExhausted
+	}
+
+	if c.PerUserLimit > 0 {
+		var used int64
+		if err := db.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ?", c.ID, userID).
+			Count(&used).Error; err != nil {
+			return nil, zero, err
+		}
+		if used >= int64(c.PerUserLimit) {
+			return nil, zero, ErrCouponUserLimit
+		}
+	}
+
+	if c.FirstOrderOnly {
+		if err := checkFirstOrder(db, userID, excludeOrderID); err != nil {
+			return nil, zero, err
+		}
+	}
+
+	discount, err := pricing.CouponDiscount(bill, pricing.CouponRule{
+		Kind:         pricing.CouponKind(c.Type),
+		PercentBps:   c.PercentBps,
+		FlatAmount:   c.FlatAmount,
+		MinOrder:     c.MinOrder,
+		MaxDiscount:  c.MaxDiscount,
+		RestaurantID: c.RestaurantID,
+	})
+	if err != nil {
+		return nil, zero, err
+	}
+	return &c, discount, nil
+}
+
+// Redeem counts a use of the coupon against orderID. Call it inside the
+// checkout transaction after Evaluate. The conditional UPDATE is what makes
+// the global limit safe under concurrency, and the row lock it takes
+// serialises concurrent redemptions so the per-user count is re-checked.
+func (s *CouponService) Redeem(tx *gorm.DB, c *models.Coupon, userID, orderID uint, discount models.Money) error {
+	res := tx.Model(&models.Coupon{}).
+		Where("id = ? AND active AND (usage_limit = 0 OR used_count < usage_limit)", c.ID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrCouponExhausted
+	}
+
+	if c.PerUserLimit > 0 {
+		var used int64
+		if err := tx.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ?", c.ID, userID).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if used >= int64(c.PerUserLimit) {
+			return ErrCouponUserLimit
+		}
+	}
+
+	// Checked again with the user's row locked. Checkout takes that lock
+	// first thing, so any other order of theirs has either committed and is
+	// counted here, or is waiting for this one.
+	if c.FirstOrderOnly {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+		if err := checkFirstOrder(tx, userID, orderID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&models.CouponRedemption{
+		CouponID: c.ID,
+		UserID:   userID,
+		OrderID:  orderID,
+		Discount: discount,
+	}).Error
+}
+
+// Unredeem gives back the use of a coupon on an order that was cancelled or
+// rejected. It runs in the transaction that ends the order, so the redemption
+// and the coupon's used_count go back together. Orders without a coupon are
+// left alone.
+func (s *CouponService) Unredeem(tx *gorm.DB, orderID uint) error {
+	var r models.CouponRedemption
+	err := tx.Clauses(clause.Returning{}).Where("order_id = ?", orderID).Delete(&r).Error
+	if err != nil || r.CouponID == 0 {
+		return err
+	}
+	return tx.Model(&models.Coupon{}).
+		Where("id = ? AND used_count > 0", r.CouponID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// checkFirstOrder fails with ErrCouponFirstOnly if the user has a live order
+// other than excludeOrderID
+func checkFirstOrder(db *gorm.DB, userID, excludeOrderID uint) error {
+	var previous int64
+	if err := db.Model(&models.Order{}).
+		Where("user_id = ? AND id <> ? AND status NOT IN ?", userID, excludeOrderID,
+			[]models.OrderStatus{models.OrderCancelled, models.OrderRejected}).
+		Count(&previous).Error; err != nil {
+		return err
+	}
+	if previous > 0 {
+		return ErrCouponFirstOnly
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/models"
+)
+
+var (
+	ErrNotSaved         = errors.New("item is not in your saved-for-later list")
+	ErrFavoriteNotFound = errors.New("nothing to favourite with that ID")
+)
+
+// FavoritesService stores the user's saved-for-later items and favourite
+// dishes and restaurants
+type FavoritesService struct {
+	DB *gorm.DB
+}
+
+// SaveForLater stores a cart line on the saved list. Saving a product that is
+// already there replaces its quantity and price.
+func (s *FavoritesService) SaveForLater(ctx context.Context, userID uint, item models.CartItem) (*models.SavedItem, error) {
+	saved := &models.SavedItem{
+		UserID:    userID,
+		ProductID: item.ProductID,
+		Quantity:  item.Quantity,
+		Price:     item.Price,
+	}
+	err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity", "price", "updated_at"}),
+	}).Create(saved).Error
+	if err != nil {
+		return nil, fmt.Errorf("save for later: %w", err)
+	}
+	return saved, nil
+}
+
+// SavedItem returns one saved line, or ErrNotSaved
+func (s *FavoritesService) SavedItem(ctx context.Context, userID, productID uint) (*models.SavedItem, error) {
+	var saved models.SavedItem
+	err := s.DB.WithContext(ctx).Where("user_id = ? AND product_id = ?", userID, productID).First(&saved).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotSaved
+	}
+	return &saved, err
+}
+
+// SavedItems lists the saved lines, most recently saved first
+func (s *FavoritesService) SavedItems(ctx context.Context, userID uint) ([]models.SavedItem, error) {
+	var out []models.SavedItem
+	err := s.DB.WithContext(ctx).Where("user_id = ?", userID).Order("updated_at DESC").Find(&out).Error
+	return out, err
+}
+
+// RemoveSaved drops a product from the saved list
+func (s *FavoritesService) RemoveSaved(ctx context.Context, userID, productID uint) error {
+	return s.DB.WithContext(ctx).
+		Where("user_id = ? AND product_id = ?", userID, productID).
+		Delete(&models.SavedItem{}).Error
+}
+
+// AddFavorite marks a product or restaurant as a favourite. Adding one that
+// is already a favourite returns the existing row.
+func (s *FavoritesService) AddFavorite(ctx context.Context, userID uint, kind models.FavoriteKind, targetID uint) (*models.Favorite, error) {
+	db := s.DB.WithContext(ctx)
+
+	var count int64
+	var err error
+	switch kind {
+	case models.FavoriteProduct:
+		err = db.Model(&models.Product{}).Where("id = ?", targetID).Count(&count).Error
+	case models.FavoriteRestaurant:
+		err = db.Model(&models.User{}).Where("id = ? AND role = ?", targetID, "admin").Count(&count).Error
+	default:
+		return nil, fmt.Errorf("unknown favourite kind %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up favourite: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrFavoriteNotFound
+	}
+
+	fav := models.Favorite{UserID: userID, Kind: kind, TargetID: targetID}
+	if err := db.Where(fav).FirstOrCreate(&fav).Error; err != nil {
+		return nil, fmt.Errorf("add favourite: %w", err)
+	}
+	return &fav, nil
+}
+
+// RemoveFavorite unmarks a favourite; removing one that isn't there is fine
+func (s *FavoritesService) RemoveFavorite(ctx context.Context, userID uint, kind models.FavoriteKind, targetID uint) error {
+	return s.DB.WithContext(ctx).
+		Where("user_id = ? AND kind = ? AND target_id = ?", userID, kind, targetID).
+		Delete(&models.Favorite{}).Error
+}
+
+// Favorites lists the user's favourites, newest first. An empty kind lists both.
+func (s *FavoritesService) Favorites(ctx context.Context, userID uint, kind models.FavoriteKind) ([]models.Favorite, error) {
+	q := s.DB.WithContext(ctx).Where("user_id = ?", userID)
+	if kind != "" {
+		q = q.Where("kind = ?", kind)
+	}
+	var out []models.Favorite
+	err := q.Order("created_at DESC").Find(&out).Error
+	return out, err
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+
+	_ "image/gif" // register GIF decoder
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/storage"
+)
+
+var (
+	ErrImageTooLarge       = errors.New("image exceeds maximum upload size")
+	ErrUnsupportedImage    = errors.New("unsupported image type (jpeg, png or gif only)")
+	ErrImageDimensionLimit = errors.New("image dimensions are too large")
+	ErrUnknownImageVariant = errors.New("unknown image variant")
+)
+
+const (
+	VariantOriginal = "original"
+
+	// Guard against decompression bombs: a tiny file can declare huge dimensions
+	maxImagePixels = 40_000_000
+)
+
+// ThumbnailSizes maps a variant name to the longest side in pixels.
+var ThumbnailSizes = map[string]int{
+	"sm": 128,
+	"md": 400,
+}
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// ImageService validates uploads, generates thumbnails and stores everything
+// in a BlobStore.
+type ImageService struct {
+	DB       *gorm.DB
+	Store    storage.BlobStore
+	MaxBytes int64
+	BaseURL  string // public URL prefix used to build image links
+}
+
+// Upload reads an image, validates it and stores the original plus one
+// thumbnail per entry in ThumbnailSizes.
+func (s *ImageService) Upload(ctx context.Context, ownerID uint, r io.Reader) (*models.ImageAsset, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read upload: %w", err)
+	}
+	if int64(len(data)) > s.MaxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	// Trust the bytes, not the client-supplied Content-Type header
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageDimensionLimit
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	asset := &models.ImageAsset{
+		ID:          uuid.NewString(),
+		OwnerID:     ownerID,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+	}
+
+	if err := s.Store.Put(ctx, blobKey(asset.ID, VariantOriginal), bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, fmt.Errorf("store original: %w", err)
+	}
+
+	for variant, side := range ThumbnailSizes {
+		var buf bytes.Buffer
+		thumb := Thumbnail(img, side)
+		if thumbnailType(contentType) == "image/jpeg" {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, thumb)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode %s thumbnail: %w", variant, err)
+		}
+		if err := s.Store.Put(ctx, blobKey(asset.ID, variant), &buf, int64(buf.Len()), thumbnailType(contentType)); err != nil {
+			return nil, fmt.Errorf("store %s thumbnail: %w", variant, err)
+		}
+	}
+
+	if err := s.DB.WithContext(ctx).Create(asset).Error; err != nil {
+		return nil, fmt.Errorf("save image asset: %w", err)
+	}
+	return asset, nil
+}
+
+// Open returns the bytes and content type of one variant of an asset.
+func (s *ImageService) Open(ctx context.Context, id, variant string) (io.ReadCloser, string, error) {
+	if variant != VariantOriginal {
+		if _, ok := ThumbnailSizes[variant]; !ok {
+			return nil, "", ErrUnknownImageVariant
+		}
+	}
+
+	var asset models.ImageAsset
+	if err := s.DB.WithContext(ctx).First(&asset, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", storage.ErrNotFound
+		}
+		return nil, "", err
+	}
+
+	rc, err := s.Store.Get(ctx, blobKey(asset.ID, variant))
+	if err != nil {
+		return nil, "", err
+	}
+	if variant == VariantOriginal {
+		return rc, asset.ContentType, nil
+	}
+	return rc, thumbnailType(asset.ContentType), nil
+}
+
+// Exists reports whether an image asset with this ID has been uploaded.
+func (s *ImageService) Exists(ctx context.Context, id string) bool {
+	var count int64
+	s.DB.WithContext(ctx).Model(&models.ImageAsset{}).Where("id = ?", id).Count(&count)
+	return count > 0
+}
+
+// URL builds the public link for an asset variant.
+func (s *ImageService) URL(id, variant string) string {
+	u := strings.TrimRight(s.BaseURL, "/") + "/images/" + id
+	if variant != "" && variant != VariantOriginal {
+		u += "?variant=" + variant
+	}
+	return u
+}
+
+// IsAssetID tells uploaded asset IDs apart from legacy image URLs stored in
+// Product.Image.
+func IsAssetID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil && len(s) == 36
+}
+
+func blobKey(id, variant string) string {
+	return "images/" + id + "/" + variant
+}
+
+// Thumbnails are JPEG for JPEG sources and PNG otherwise (keeps transparency).
+func thumbnailType(originalType string) string {
+	if originalType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
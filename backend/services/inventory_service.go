@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/events"
+	"swiggy-clone/backend/models"
+)
+
+var (
+	ErrNegativeStock    = errors.New("stock cannot go below zero")
+	ErrReservationEnded = errors.New("the stock hold for this order has ended; please checkout again")
+)
+
+// InsufficientStockError is returned when an order asks for more than is on hand
+type InsufficientStockError struct {
+	ProductID uint
+	Requested int
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for product %d: requested %d, available %d", e.ProductID, e.Requested, e.Available)
+}
+
+// StockLine is a product/quantity pair to take out of stock
+type StockLine struct {
+	ProductID uint
+	Quantity  int
+}
+
+// InventoryService owns every change to Product.Stock. Each change is written
+// to the inventory_movements ledger in the same transaction.
+type InventoryService struct {
+	DB             *gorm.DB
+	ReservationTTL time.Duration
+	Notifications  *NotificationService // low-stock / out-of-stock alerts, optional
+	Orders         *OrderService        // cancels orders whose hold ran out
+	Events         *events.Bus          // gets a StockLow when stock drops to the threshold
+}
+
+// Reserve takes stock for a freshly created order and holds it until the
+// customer pays. It must run inside the checkout transaction; product rows are
+// locked (in ID order, to avoid deadlocks) and returned so the caller can
+// snapshot them.
+func (s *InventoryService) Reserve(tx *gorm.DB, userID, orderID uint, lines []StockLine) (map[uint]models.Product, error) {
+	qty := map[uint]int{}
+	ids := []uint{}
+	for _, l := range lines {
+		if l.Quantity <= 0 {
+			return nil, fmt.Errorf("invalid quantity %d for product %d", l.Quantity, l.ProductID)
+		}
+		if _, seen := qty[l.ProductID]; !seen {
+			ids = append(ids, l.ProductID)
+		}
+		qty[l.ProductID] += l.Quantity
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var products []models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id").
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	locked := make(map[uint]models.Product, len(products))
+	for _, p := range products {
+		locked[p.ID] = p
+	}
+
+	expiresAt := time.Now().Add(s.ReservationTTL)
+	for _, id := range ids {
+		p, ok := locked[id]
+		if !ok {
+			return nil, fmt.Errorf("product %d no longer exists", id)
+		}
+		if qty[id] > p.Stock {
+			return nil, &InsufficientStockError{ProductID: id, Requested: qty[id], Available: p.Stock}
+		}
+
+		oid := orderID
+		after, err := s.apply(tx, &p, -qty[id], models.MovementOrder, &oid, &userID, "")
+		if err != nil {
+			return nil, err
+		}
+		p.Stock = after
+		locked[id] = p
+
+		if err := tx.Create(&models.StockReservation{
+			UserID:    userID,
+			OrderID:   orderID,
+			ProductID: id,
+			Quantity:  qty[id],
+			Status:    models.ReservationActive,
+			ExpiresAt: expiresAt,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("create reservation: %w", err)
+		}
+	}
+	return locked, nil
+}
+
+// Consume marks an order's reservations as paid for. The stock was already
+// taken at checkout so nothing moves in the ledger. An order whose hold was
+// released or expired has nothing left to consume and can't be paid for.
+// Lock the order row first, as Release does.
+func (s *InventoryService) Consume(tx *gorm.DB, orderID uint) error {
+	res := tx.Model(&models.StockReservation{}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Update("status", models.ReservationConsumed)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrReservationEnded
+	}
+	return nil
+}
+
+// Release puts an unpaid order's reserved stock back and cancels the order.
+// Used when a payment fails (RELEASED) or the hold times out (EXPIRED).
+func (s *InventoryService) Release(ctx context.Context, orderID uint, status models.ReservationStatus, note string) error {
+	var cancelled *models.Order
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The order is locked before its reservations, the same order payment
+		// takes them in, so a payment and a release can't deadlock
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&order, orderID).Error; err != nil {
+			return err
+		}
+		returned, err := s.returnReservations(tx, orderID, []models.ReservationStatus{models.ReservationActive}, status, note)
+		if err != nil {
+			return err
+		}
+		if returned == 0 {
+			return nil // already consumed or released
+		}
+
+		// Only an order nobody has acted on yet is cancelled here
+		if order.Status != models.OrderPlaced {
+			return nil
+		}
+		cancelled, err = s.Orders.TransitionTx(tx, orderID, models.OrderCancelled, SystemActor, note)
+		if err != nil {
+			return err
+		}
+		return s.Orders.Coupons.Unredeem(tx, orderID)
+	})
+	if err != nil {
+		return err
+	}
+	s.Orders.Publish(ctx, cancelled)
+	return nil
+}
+
+// ReturnOrderStock puts back the stock of an order that won't be fulfilled,
+// paid for or not. It must run inside the transaction that ends the order.
+func (s *InventoryService) ReturnOrderStock(tx *gorm.DB, orderID uint, note string) error {
+	_, err := s.returnReservations(tx, orderID,
+		[]models.ReservationStatus{models.ReservationActive, models.ReservationConsumed},
+		models.ReservationReleased, note)
+	return err
+}
+
+// HasActiveReservation reports whether an order still holds unpaid stock
+func (s *InventoryService) HasActiveReservation(tx *gorm.DB, orderID uint) (bool, error) {
+	var n int64
+	err := tx.Model(&models.StockReservation{}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// returnReservations puts the stock of the order's reservations in one of
+// from back on the shelf and marks them to. It returns how many it found.
+func (s *InventoryService) returnReservations(tx *gorm.DB, orderID uint, from []models.ReservationStatus, to models.ReservationStatus, note string) (int, error) {
+	var reservations []models.StockReservation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, from).
+		Order("product_id").
+		Find(&reservations).Error; err != nil {
+		return 0, err
+	}
+
+	for _, res := range reservations {
+		oid := res.OrderID
+		if _, err := s.adjust(tx, res.ProductID, res.Quantity, models.MovementCancellation, &oid, nil, note); err != nil {
+			return 0, err
+		}
+		if err := tx.Model(&res).Update("status", to).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(reservations), nil
+}
+
+// ReleaseExpired releases every reservation whose hold has run out
+func (s *InventoryService) ReleaseExpired(ctx context.Context) error {
+	var orderIDs []uint
+	if err := s.DB.WithContext(ctx).Model(&models.StockReservation{}).
+		Where("status = ? AND expires_at < ?", models.ReservationActive, time.Now()).
+		Distinct().
+		Pluck("order_id", &orderIDs).Error; err != nil {
+		return err
+	}
+
+	for _, id := range orderIDs {
+		if err := s.Release(ctx, id, models.ReservationExpired, "reservation expired"); err != nil {
+			log.Printf("inventory: failed to release expired reservation for order %d: %v", id, err)
+			continue
+		}
+		log.Printf("inventory: released expired reservation for order %d", id)
+	}
+	return nil
+}
+
+// RunReaper releases expired reservations every interval until ctx is done
+func (s *InventoryService) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ReleaseExpired(ctx); err != nil {
+				log.Printf("inventory: reaper error: %v", err)
+			}
+		}
+	}
+}
+
+// Restock adds delivered stock
+func (s *InventoryService) Restock(ctx context.Context, actorID, productID uint, quantity int, note string) (*models.Product, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("restock quantity must be positive")
+	}
+	return s.change(ctx, actorID, productID, quantity, models.MovementRestock, note)
+}
+
+// RestockTx is Restock inside the caller's transaction
+func (s *InventoryService) RestockTx(tx *gorm.DB, actorID, productID uint, quantity int, note string) (*models.Product, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("restock quantity must be positive")
+	}
+	return s.changeTx(tx, actorID, productID, quantity, models.MovementRestock, note)
+}
+
+// Adjust applies a manual correction (stocktake, damaged goods...)
+func (s *InventoryService) Adjust(ctx context.Context, actorID, productID uint, delta int, note string) (*models.Product, error) {
+	if delta == 0 {
+		return nil, fmt.Errorf("adjustment delta cannot be zero")
+	}
+	return s.change(ctx, actorID, productID, delta, models.MovementAdjustment, note)
+}
+
+// AdjustTx is Adjust inside the caller's transaction
+func (s *InventoryService) AdjustTx(tx *gorm.DB, actorID, productID uint, delta int, note string) (*models.Product, error) {
+	if delta == 0 {
+		return nil, fmt.Errorf("adjustment delta cannot be zero")
+	}
+	return s.changeTx(tx, actorID, productID, delta, models.MovementAdjustment, note)
+}
+
+// SetLowStockThreshold changes the level at which low-stock alerts fire
+func (s *InventoryService) SetLowStockThreshold(ctx context.Context, productID uint, threshold int) (*models.Product, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("threshold cannot be negative")
+	}
+	var p models.Product
+	if err := s.DB.WithContext(ctx).Model(&models.Product{}).
+		Where("id = ?", productID).
+		Update("low_stock_threshold", threshold).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.WithContext(ctx).First(&p, productID).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LowStock lists an admin's products at or below their threshold, emptiest first
+func (s *InventoryService) LowStock(ctx context.Context, adminID uint) ([]models.Product, error) {
+	var products []models.Product
+	err := s.DB.WithContext(ctx).
+		Where("admin_id = ? AND stock <= low_stock_threshold", adminID).
+		Order("stock ASC, name ASC").
+		Find(&products).Error
+	return products, err
+}
+
+// Ledger returns the most recent movements for a product
+func (s *InventoryService) Ledger(ctx context.Context, productID uint, limit int) ([]models.InventoryMovement, error) {
+	var movements []models.InventoryMovement
+	err := s.DB.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&movements).Error
+	return movements, err
+}
+
+func (s *InventoryService) change(ctx context.Context, actorID, productID uint, delta int, reason models.MovementReason, note string) (*models.Product, error) {
+	var product *models.Product
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		product, err = s.changeTx(tx, actorID, productID, delta, reason, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *InventoryService) changeTx(tx *gorm.DB, actorID, productID uint, delta int, reason models.MovementReason, note string) (*models.Product, error) {
+	if _, err := s.adjust(tx, productID, delta, reason, nil, &actorID, note); err != nil {
+		return nil, err
+	}
+	var product models.Product
+	if err := tx.First(&product, productID).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// adjust locks a product row and applies a ledger movement to it
+func (s *InventoryService) adjust(tx *gorm.DB, productID uint, delta int, reason models.MovementReason, orderID, actorID *uint, note string) (int, error) {
+	var p models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, productID).Error; err != nil {
+		return 0, fmt.Errorf("product %d: %w", productID, err)
+	}
+	return s.apply(tx, &p, delta, reason, orderID, actorID, note)
+}
+
+// apply updates stock on an already-locked product and writes the ledger row
+func (s *InventoryService) apply(tx *gorm.DB, p *models.Product, delta int, reason models.MovementReason, orderID, actorID *uint, note string) (int, error) {
+	after := p.Stock + delta
+	if after < 0 {
+		return 0, ErrNegativeStock
+	}
+
+	// Nothing on the shelf means not orderable. Availability comes back with
+	// the stock only if running out is what switched it off.
+	columns := map[string]interface{}{"stock": gorm.Expr("stock + ?", delta)}
+	switch {
+	case after == 0 && p.IsAvailable:
+		columns["is_available"], columns["sold_out"] = false, true
+		p.IsAvailable, p.SoldOut = false, true
+	case p.Stock == 0 && after > 0 && p.SoldOut:
+		columns["is_available"], columns["sold_out"] = true, false
+		p.IsAvailable, p.SoldOut = true, false
+	}
+	if err := tx.Model(&models.Product{}).
+		Where("id = ?", p.ID).
+		UpdateColumns(columns).Error; err != nil {
+		return 0, fmt.Errorf("stock update failed: %w", err)
+	}
+
+	if err := tx.Create(&models.InventoryMovement{
+		ProductID:  p.ID,
+		Delta:      delta,
+		StockAfter: after,
+		Reason:     reason,
+		OrderID:    orderID,
+		ActorID:    actorID,
+		Note:       note,
+	}).Error; err != nil {
+		return 0, fmt.Errorf("ledger write failed: %w", err)
+	}
+
+	if s.Notifications != nil {
+		if err := s.Notifications.stockAlert(tx, p, p.Stock, after); err != nil {
+			return 0, err
+		}
+	}
+	if (p.Stock > p.LowStockThreshold && after <= p.LowStockThreshold) || (p.Stock > 0 && after == 0) {
+		if err := s.Events.Publish(tx, events.StockLow{
+			ProductID: p.ID,
+			AdminID:   p.AdminID,
+			Name:      p.Name,
+			Stock:     after,
+			Threshold: p.LowStockThreshold,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return after, nil
+}
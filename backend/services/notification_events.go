@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/events"
+	"swiggy-clone/backend/models"
+)
+
+// Subscribe lets restaurant admins know when an order comes in and when it
+// has been paid, which is when they can accept it
+func (s *NotificationService) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "notify-new-order", 4, func(ctx context.Context, ev events.OrderPlaced) error {
+		return s.notifyAdmins(ctx, events.EventID(ctx), ev.Admins, models.NotificationNewOrder,
+			fmt.Sprintf("New order #%d is waiting to be accepted", ev.OrderID))
+	})
+	events.Subscribe(bus, "notify-order-paid", 4, func(ctx context.Context, ev events.PaymentCaptured) error {
+		return s.notifyAdmins(ctx, events.EventID(ctx), ev.Admins, models.NotificationOrderPaid,
+			fmt.Sprintf("Order #%d has been paid and can be accepted", ev.OrderID))
+	})
+}
+
+// notifyAdmins notifies each admin once per event: a redelivered event finds
+// the (event, admin) pair taken and skips the admins it already reached
+func (s *NotificationService) notifyAdmins(ctx context.Context, eventID string, admins []string, kind models.NotificationKind, message string) error {
+	var errs []error
+	for _, admin := range admins {
+		var adminID uint
+		if _, err := fmt.Sscanf(admin, "%d", &adminID); err != nil {
+			continue
+		}
+		n := &models.Notification{UserID: adminID, Kind: kind, Message: message}
+		if eventID != "" {
+			n.EventID = &eventID
+		}
+		res := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(n)
+		if res.Error != nil {
+			errs = append(errs, fmt.Errorf("notify admin %d: %w", adminID, res.Error))
+			continue
+		}
+		if res.RowsAffected > 0 {
+			log.Printf("🔔 [%s] user %d: %s", kind, adminID, message)
+		}
+	}
+	return errors.Join(errs...)
+}
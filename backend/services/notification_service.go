@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"swiggy-clone/backend/models"
+)
+
+// NotificationService stores in-app notifications for users
+type NotificationService struct {
+	DB *gorm.DB
+}
+
+// Notify records a notification. Pass the current transaction so the
+// notification only exists if the change that caused it commits.
+func (s *NotificationService) Notify(tx *gorm.DB, userID uint, kind models.NotificationKind, message string, productID *uint) error {
+	if err := tx.Create(&models.Notification{
+		UserID:    userID,
+		Kind:      kind,
+		Message:   message,
+		ProductID: productID,
+	}).Error; err != nil {
+		return fmt.Errorf("notification write failed: %w", err)
+	}
+	log.Printf("🔔 [%s] user %d: %s", kind, userID, message)
+	return nil
+}
+
+// List returns a user's notifications, newest first
+func (s *NotificationService) List(ctx context.Context, userID uint, unreadOnly bool) ([]models.Notification, error) {
+	q := s.DB.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+	var out []models.Notification
+	err := q.Order("created_at DESC").Limit(100).Find(&out).Error
+	return out, err
+}
+
+// MarkRead marks one of the user's notifications as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id uint) error {
+	res := s.DB.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	return res.Error
+}
+
+// stockAlert decides whether a stock change crossed the product's low-stock
+// threshold or emptied it, and notifies the owning admin.
+func (s *NotificationService) stockAlert(tx *gorm.DB, p *models.Product, before, after int) error {
+	pid := p.ID
+	switch {
+	case before > 0 && after == 0:
+		return s.Notify(tx, p.AdminID, models.NotificationOutOfStock,
+			fmt.Sprintf("%s is out of stock and has been marked unavailable", p.Name), &pid)
+	case before > p.LowStockThreshold && after <= p.LowStockThreshold && after > 0:
+		return s.Notify(tx, p.AdminID, models.NotificationLowStock,
+			fmt.Sprintf("%s is running low: %d left (threshold %d)", p.Name, after, p.LowStockThreshold), &pid)
+	}
+	return nil
+}
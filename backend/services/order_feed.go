@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"swiggy-clone/backend/redis"
+)
+
+// OrderFeed fans the instance's single Redis subscription out to every
+// GraphQL subscription connected to it
+type OrderFeed struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]*orderListener
+}
+
+type orderListener struct {
+	match func(redis.OrderEvent) bool
+	ch    chan redis.OrderEvent
+}
+
+func NewOrderFeed() *OrderFeed {
+	return &OrderFeed{subs: map[int]*orderListener{}}
+}
+
+// Run reads order events from Redis and hands them out until ctx is done
+func (f *OrderFeed) Run(ctx context.Context) {
+	for ev := range redis.SubscribeOrderEvents(ctx) {
+		f.mu.Lock()
+		for id, l := range f.subs {
+			if !l.match(ev) {
+				continue
+			}
+			// A listener that isn't keeping up misses events rather than
+			// holding up everyone else
+			select {
+			case l.ch <- ev:
+			default:
+				log.Printf("order feed: listener %d is behind, dropped event for order %d", id, ev.OrderID)
+			}
+		}
+		f.mu.Unlock()
+	}
+}
+
+// Subscribe returns the events match accepts. The channel closes when ctx is done.
+func (f *OrderFeed) Subscribe(ctx context.Context, match func(redis.OrderEvent) bool) <-chan redis.OrderEvent {
+	l := &orderListener{match: match, ch: make(chan redis.OrderEvent, 16)}
+	f.mu.Lock()
+	id := f.next
+	f.next++
+	f.subs[id] = l
+	f.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		delete(f.subs, id)
+		close(l.ch)
+		f.mu.Unlock()
+	}()
+	return l.ch
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/events"
+	"swiggy-clone/backend/kafka"
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+)
+
+var (
+	ErrOrderNotFound = errors.New("order not found")
+	ErrOrderUnpaid   = errors.New("order hasn't been paid yet")
+)
+
+// InvalidTransitionError is returned for a status change the lifecycle doesn't allow
+type InvalidTransitionError struct {
+	From, To models.OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("order cannot go from %s to %s", e.From, e.To)
+}
+
+// orderTransitions is the order state machine: the statuses each status may
+// move to. Terminal statuses have no entry.
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderPlaced:    {models.OrderAccepted, models.OrderRejected, models.OrderCancelled},
+	models.OrderAccepted:  {models.OrderPreparing, models.OrderCancelled},
+	models.OrderPreparing: {models.OrderReady, models.OrderCancelled},
+	models.OrderReady:     {models.OrderPickedUp, models.OrderCancelled},
+	models.OrderPickedUp:  {models.OrderDelivered},
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to models.OrderStatus) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderActorRef says who made a status change; ID is nil for the system
+type OrderActorRef struct {
+	Type models.OrderActor
+	ID   *uint
+}
+
+func UserActor(id uint) OrderActorRef  { return OrderActorRef{Type: models.ActorUser, ID: &id} }
+func AdminActor(id uint) OrderActorRef { return OrderActorRef{Type: models.ActorAdmin, ID: &id} }
+
+var SystemActor = OrderActorRef{Type: models.ActorSystem}
+
+// orderPath is the happy path, used to walk an order forward through the
+// steps in between (e.g. ACCEPTED straight to READY passes PREPARING)
+var orderPath = []models.OrderStatus{
+	models.OrderPlaced, models.OrderAccepted, models.OrderPreparing,
+	models.OrderReady, models.OrderPickedUp, models.OrderDelivered,
+}
+
+// OrderService owns order status changes. Every change goes through
+// TransitionTx so it is validated and lands in order_status_history.
+type OrderService struct {
+	DB        *gorm.DB
+	Inventory *InventoryService // returns stock of rejected and cancelled orders
+	Coupons   *CouponService    // gives back the coupon of rejected and cancelled orders
+	Events    *events.Bus       // gets an OrderStatusChanged for every transition
+
+	Refunds RefundPolicy // how much a customer gets back on cancelling
+
+	// Paid orders still PLACED after this long are rejected automatically
+	AcceptWindow time.Duration
+}
+
+// Placed records the initial PLACED entry for a new order, inside the
+// checkout transaction
+func (s *OrderService) Placed(tx *gorm.DB, order *models.Order, actor OrderActorRef) error {
+	return s.record(tx, order.ID, "", models.OrderPlaced, actor, "order placed")
+}
+
+// Transition moves an order to a new status in its own transaction
+func (s *OrderService) Transition(ctx context.Context, orderID uint, to models.OrderStatus, actor OrderActorRef, reason string) (*models.Order, error) {
+	var order *models.Order
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.TransitionTx(tx, orderID, to, actor, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Publish(ctx, order)
+	return order, nil
+}
+
+// TransitionTx moves an order to a new status inside tx. The order row is
+// locked so two concurrent changes can't both pass validation.
+func (s *OrderService) TransitionTx(tx *gorm.DB, orderID uint, to models.OrderStatus, actor OrderActorRef, reason string) (*models.Order, error) {
+	var order models.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	from := order.Status
+	if !CanTransition(from, to) {
+		return nil, &InvalidTransitionError{From: from, To: to}
+	}
+	if err := tx.Model(&order).Update("status", to).Error; err != nil {
+		return nil, fmt.Errorf("update order status: %w", err)
+	}
+	if err := s.record(tx, order.ID, from, to, actor, reason); err != nil {
+		return nil, err
+	}
+	order.Status = to
+	if err := s.Events.Publish(tx, events.OrderStatusChanged{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Admins:  order.ProductAdmins,
+		From:    from,
+		To:      to,
+		Actor:   actor.Type,
+		ActorID: actor.ID,
+		Reason:  reason,
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📦 order %d: %s → %s (%s)", order.ID, from, to, actor.Type)
+	return &order, nil
+}
+
+func (s *OrderService) record(tx *gorm.DB, orderID uint, from, to models.OrderStatus, actor OrderActorRef, reason string) error {
+	if err := tx.Create(&models.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		Reason:     reason,
+	}).Error; err != nil {
+		return fmt.Errorf("order history write failed: %w", err)
+	}
+	return nil
+}
+
+// Accept confirms the order and records when it should be ready. Unpaid
+// orders can't be accepted: their stock is only held for a while.
+func (s *OrderService) Accept(ctx context.Context, orderID uint, actor OrderActorRef, prepMinutes int) (*models.Order, error) {
+	if prepMinutes <= 0 || prepMinutes > 240 {
+		return nil, fmt.Errorf("prep time must be between 1 and 240 minutes")
+	}
+	var order *models.Order
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		unpaid, err := s.Inventory.HasActiveReservation(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if unpaid {
+			return ErrOrderUnpaid
+		}
+
+		order, err = s.TransitionTx(tx, orderID, models.OrderAccepted, actor,
+			fmt.Sprintf("ready in about %d minutes", prepMinutes))
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		readyBy := now.Add(time.Duration(prepMinutes) * time.Minute)
+		order.PrepMinutes, order.AcceptedAt, order.ReadyBy = &prepMinutes, &now, &readyBy
+		return tx.Model(order).Updates(map[string]interface{}{
+			"prep_minutes": prepMinutes,
+			"accepted_at":  now,
+			"ready_by":     readyBy,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Publish(ctx, order)
+	return order, nil
+}
+
+// Reject turns an order down: its stock goes back on the shelf, its coupon can
+// be used again and any payment for it is refunded in full
+func (s *OrderService) Reject(ctx context.Context, orderID uint, actor OrderActorRef, reason string) (*models.Order, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to reject an order")
+	}
+	var order *models.Order
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.TransitionTx(tx, orderID, models.OrderRejected, actor, reason)
+		if err != nil {
+			return err
+		}
+		if err := s.Inventory.ReturnOrderStock(tx, orderID, "order rejected: "+reason); err != nil {
+			return err
+		}
+		if err := s.Coupons.Unredeem(tx, orderID); err != nil {
+			return err
+		}
+		return s.refund(tx, order, 10000, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Publish(ctx, order)
+	return order, nil
+}
+
+// Advance walks an order forward along the happy path to the given status,
+// recording each step in between
+func (s *OrderService) Advance(ctx context.Context, orderID uint, to models.OrderStatus, actor OrderActorRef, reason string) (*models.Order, error) {
+	target := -1
+	for i, st := range orderPath {
+		if st == to {
+			target = i
+		}
+	}
+	if target < 0 {
+		return nil, fmt.Errorf("%s is not a step forward", to)
+	}
+
+	var order *models.Order
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Order
+		if err := tx.Select("id", "status").First(&current, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+		from := -1
+		for i, st := range orderPath {
+			if st == current.Status {
+				from = i
+			}
+		}
+		// A PLACED order has to go through Accept first
+		if from < 1 || from >= target {
+			return &InvalidTransitionError{From: current.Status, To: to}
+		}
+		for i := from + 1; i <= target; i++ {
+			var err error
+			if order, err = s.TransitionTx(tx, orderID, orderPath[i], actor, reason); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Publish(ctx, order)
+	return order, nil
+}
+
+// RejectStale rejects paid orders that are still PLACED after AcceptWindow.
+// Orders from before the lifecycle are left alone: a PENDING order migrated to
+// PLACED may have been paid and served under the old flow, which had no
+// reservation or accept step.
+func (s *OrderService) RejectStale(ctx context.Context) error {
+	if s.AcceptWindow <= 0 {
+		return nil
+	}
+	var ids []uint
+	if err := s.DB.WithContext(ctx).Model(&models.Order{}).
+		Where("status = ? AND placed_at < ?", models.OrderPlaced, time.Now().Add(-s.AcceptWindow)).
+		Where("legacy_status IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM stock_reservations r WHERE r.order_id = orders.id AND r.status = ?)",
+			models.ReservationActive).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("restaurant didn't accept the order within %s", s.AcceptWindow)
+	for _, id := range ids {
+		if _, err := s.Reject(ctx, id, SystemActor, reason); err != nil {
+			// Accepted in the meantime; nothing to do
+			var invalid *InvalidTransitionError
+			if !errors.As(err, &invalid) {
+				log.Printf("orders: failed to auto-reject order %d: %v", id, err)
+			}
+			continue
+		}
+		log.Printf("orders: auto-rejected order %d", id)
+	}
+	return nil
+}
+
+// RunAutoReject runs RejectStale every interval until ctx is done
+func (s *OrderService) RunAutoReject(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RejectStale(ctx); err != nil {
+				log.Printf("orders: auto-reject error: %v", err)
+			}
+		}
+	}
+}
+
+// Publish tells subscribers on every instance that an order changed. Call it
+// once the transaction that changed the order has committed; a failed publish
+// is only logged since the change itself already happened.
+func (s *OrderService) Publish(ctx context.Context, order *models.Order) {
+	s.Events.Wake()
+	if order == nil || redis.RDB == nil {
+		return
+	}
+	err := redis.PublishOrderEvent(ctx, redis.OrderEvent{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Admins:  []string(order.ProductAdmins),
+		Status:  order.Status,
+		At:      time.Now(),
+	})
+	if err != nil {
+		log.Printf("orders: failed to publish status of order %d: %v", order.ID, err)
+	}
+}
+
+// History returns an order's status changes, oldest first
+func (s *OrderService) History(ctx context.Context, orderID uint) ([]models.OrderStatusHistory, error) {
+	return s.HistorySince(ctx, orderID, 0)
+}
+
+// HistorySince returns the order's status changes after the entry with ID
+// afterID, oldest first. Entry IDs only grow, so they work as resume points.
+func (s *OrderService) HistorySince(ctx context.Context, orderID, afterID uint) ([]models.OrderStatusHistory, error) {
+	var out []models.OrderStatusHistory
+	err := s.DB.WithContext(ctx).Where("order_id = ? AND id > ?", orderID, afterID).Order("id").Find(&out).Error
+	return out, err
+}
+
+// OrderFacts reads what older versions of the order events left out from the
+// order row. A missing order can never be filled in, so it is unprocessable.
+func (s *OrderService) OrderFacts(ctx context.Context, orderID uint) (events.OrderFacts, error) {
+	var order models.Order
+	err := s.DB.WithContext(ctx).Unscoped().Select("id", "user_id", "product_admins", "total").First(&order, orderID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return events.OrderFacts{}, fmt.Errorf("%w: order %d not found", kafka.ErrUnprocessable, orderID)
+	}
+	if err != nil {
+		return events.OrderFacts{}, err
+	}
+	var methods []string
+	err = s.DB.WithContext(ctx).Model(&models.Payment{}).
+		Where("order_id = ?", fmt.Sprint(orderID)).Order("id").Limit(1).
+		Pluck("method", &methods).Error
+	if err != nil {
+		return events.OrderFacts{}, err
+	}
+	facts := events.OrderFacts{
+		UserID: order.UserID,
+		Admins: []string(order.ProductAdmins),
+		Total:  order.Total,
+	}
+	if len(methods) > 0 {
+		facts.Method = methods[0]
+	}
+	return facts, nil
+}
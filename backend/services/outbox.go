@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/kafka"
+	"swiggy-clone/backend/models"
+)
+
+// OutboxService is the transactional outbox behind the event bus. Add writes
+// an event in the caller's transaction; Relay hands committed events to the
+// queue at least once. A crash after publishing but before marking the row
+// delivered sends it again with the same event ID.
+type OutboxService struct {
+	DB    *gorm.DB
+	Queue kafka.OrderQueue
+
+	BatchSize    int
+	PollInterval time.Duration // how often the relay looks for events nobody woke it for
+	MaxBackoff   time.Duration // longest wait between attempts at an undeliverable event
+	MaxAttempts  int           // attempts before an event is marked dead
+	LeaseTimeout time.Duration // how long a claimed batch is left to one relay
+	Retention    time.Duration // delivered events are deleted after this
+
+	wake chan struct{}
+}
+
+// NewOutboxService returns an outbox that relays to queue
+func NewOutboxService(db *gorm.DB, queue kafka.OrderQueue) *OutboxService {
+	return &OutboxService{
+		DB:           db,
+		Queue:        queue,
+		BatchSize:    100,
+		PollInterval: 2 * time.Second,
+		MaxBackoff:   5 * time.Minute,
+		MaxAttempts:  20,
+		LeaseTimeout: 2 * time.Minute,
+		Retention:    7 * 24 * time.Hour,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Add records an encoded event inside tx. It is only delivered if tx commits.
+func (s *OutboxService) Add(tx *gorm.DB, ev kafka.Event) error {
+	payload, err := ev.Encode()
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(&models.OutboxEvent{
+		EventID:       ev.ID,
+		EventType:     ev.Type,
+		OrderID:       ev.OrderID,
+		Payload:       payload,
+		NextAttemptAt: ev.OccurredAt,
+		CreatedAt:     ev.OccurredAt,
+	}).Error; err != nil {
+		return fmt.Errorf("outbox write failed: %w", err)
+	}
+	return nil
+}
+
+// Wake tells the relay there is something new, once the transaction that
+// added it has committed. Without it the event still goes out on the next poll.
+func (s *OutboxService) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Relay delivers events until ctx is done. A batch under way when ctx is
+// cancelled still finishes, so what it published gets marked delivered.
+func (s *OutboxService) Relay(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for ctx.Err() == nil {
+		sent, err := s.RelayOnce(context.WithoutCancel(ctx))
+		if err != nil {
+			log.Printf("outbox: relay error: %v", err)
+		}
+		if err == nil && sent == s.BatchSize {
+			continue // probably more waiting
+		}
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-ticker.C:
+		case <-prune.C:
+			if err := s.Prune(ctx); err != nil {
+				log.Printf("outbox: prune error: %v", err)
+			}
+		}
+	}
+}
+
+// RelayOnce publishes a batch of due events in the order they were written
+// and returns how many went out. The batch is claimed in a short transaction
+// that pushes the rows' next attempt out by LeaseTimeout, with SKIP LOCKED so
+// relays on several instances never claim the same row; publishing happens
+// after it commits, so a slow queue holds no locks or connections. A relay
+// that dies mid-batch leaves rows that become due again once the lease runs
+// out, and are sent again with the same event ID.
+//
+// Events about one order go out in order: a row waits while an earlier row
+// for its order is undelivered, and a failure holds back the rest of that
+// order's rows, not the whole batch. A row that still fails after MaxAttempts,
+// or can never be published, is marked dead and stops holding its order up.
+func (s *OutboxService) RelayOnce(ctx context.Context) (int, error) {
+	rows, err := s.claim(ctx)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	db := s.DB.WithContext(ctx)
+	sent := 0
+	var failed []error
+	held := map[uint]bool{} // orders with a row that failed in this batch
+	var skipped []uint
+	for i := range rows {
+		row := &rows[i]
+		if row.OrderID != 0 && held[row.OrderID] {
+			skipped = append(skipped, row.ID)
+			continue
+		}
+		ev, perr := kafka.StoredEvent(row.Payload, row.EventID, row.EventType, row.OrderID, row.CreatedAt)
+		if perr == nil {
+			perr = s.Queue.Publish(ctx, ev)
+		}
+		if perr != nil {
+			if err := s.fail(db, row, perr); err != nil {
+				return sent, err
+			}
+			if row.DeadAt == nil {
+				held[row.OrderID] = true
+			}
+			failed = append(failed, fmt.Errorf("publish %s %s (attempt %d): %w", row.EventType, row.EventID, row.Attempts, perr))
+			continue
+		}
+		if err := db.Model(row).Update("delivered_at", time.Now()).Error; err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	// Rows held back behind a failure give up their lease; they stay blocked
+	// by the failed row until it goes out
+	if len(skipped) > 0 {
+		if err := db.Model(&models.OutboxEvent{}).Where("id IN ?", skipped).
+			Update("next_attempt_at", time.Now()).Error; err != nil {
+			return sent, err
+		}
+	}
+	return sent, errors.Join(failed...)
+}
+
+// claim leases a batch of due rows to this relay
+func (s *OutboxService) claim(ctx context.Context) ([]models.OutboxEvent, error) {
+	var rows []models.OutboxEvent
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("delivered_at IS NULL AND dead_at IS NULL AND next_attempt_at <= ?", time.Now()).
+			Where(`order_id = 0 OR NOT EXISTS (
+				SELECT 1 FROM outbox earlier
+				WHERE earlier.order_id = outbox.order_id AND earlier.id < outbox.id
+					AND earlier.delivered_at IS NULL AND earlier.dead_at IS NULL)`).
+			Order("id").
+			Limit(s.BatchSize).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(s.LeaseTimeout)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox rows: %w", err)
+	}
+	return rows, nil
+}
+
+// fail records a failed attempt at row and schedules the next one, or marks
+// the row dead once it is out of attempts
+func (s *OutboxService) fail(db *gorm.DB, row *models.OutboxEvent, cause error) error {
+	msg := cause.Error()
+	row.Attempts++
+	updates := map[string]interface{}{
+		"attempts":        row.Attempts,
+		"last_error":      msg,
+		"next_attempt_at": time.Now().Add(s.backoff(row.Attempts)),
+	}
+	if row.Attempts >= s.MaxAttempts || errors.Is(cause, kafka.ErrUnprocessable) {
+		now := time.Now()
+		row.DeadAt = &now
+		updates["dead_at"] = now
+		log.Printf("outbox: giving up on %s %s after %d attempt(s): %s", row.EventType, row.EventID, row.Attempts, msg)
+	}
+	return db.Model(row).Updates(updates).Error
+}
+
+// Prune deletes events delivered longer ago than Retention
+func (s *OutboxService) Prune(ctx context.Context) error {
+	return s.DB.WithContext(ctx).
+		Where("delivered_at < ?", time.Now().Add(-s.Retention)).
+		Delete(&models.OutboxEvent{}).Error
+}
+
+func (s *OutboxService) backoff(attempt int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempt && d < s.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, s.MaxBackoff)
+}
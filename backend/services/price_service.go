@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/redis"
+)
+
+var ErrInvalidPrice = errors.New("price must be greater than zero")
+
+// PriceService keeps the product_prices history and applies scheduled changes
+type PriceService struct {
+	DB *gorm.DB
+}
+
+// Record writes an applied price to the history. Call it in the same
+// transaction that changes Product.Price.
+func (s *PriceService) Record(tx *gorm.DB, productID uint, price models.Money, actorID uint) error {
+	now := time.Now()
+	return tx.Create(&models.ProductPrice{
+		ProductID:   productID,
+		Price:       price,
+		EffectiveAt: now,
+		Status:      models.PriceApplied,
+		CreatedBy:   actorID,
+		AppliedAt:   &now,
+	}).Error
+}
+
+// Change updates a product's price right away and records it
+func (s *PriceService) Change(ctx context.Context, actorID, productID uint, price models.Money) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.ChangeTx(tx, actorID, productID, price)
+	})
+}
+
+// ChangeTx is Change inside the caller's transaction
+func (s *PriceService) ChangeTx(tx *gorm.DB, actorID, productID uint, price models.Money) error {
+	if price.Amount <= 0 {
+		return ErrInvalidPrice
+	}
+	if err := tx.Model(&models.Product{}).Where("id = ?", productID).Update("price", price).Error; err != nil {
+		return err
+	}
+	return s.Record(tx, productID, price, actorID)
+}
+
+// Schedule queues a price change for later. Times in the past are applied
+// on the scheduler's next tick.
+func (s *PriceService) Schedule(ctx context.Context, actorID, productID uint, price models.Money, effectiveAt time.Time) (*models.ProductPrice, error) {
+	if price.Amount <= 0 {
+		return nil, ErrInvalidPrice
+	}
+	row := &models.ProductPrice{
+		ProductID:   productID,
+		Price:       price,
+		EffectiveAt: effectiveAt,
+		Status:      models.PriceScheduled,
+		CreatedBy:   actorID,
+	}
+	if err := s.DB.WithContext(ctx).Create(row).Error; err != nil {
+		return nil, fmt.Errorf("schedule price change: %w", err)
+	}
+	return row, nil
+}
+
+// Cancel drops a scheduled change that hasn't been applied yet
+func (s *PriceService) Cancel(ctx context.Context, id uint) (*models.ProductPrice, error) {
+	var row models.ProductPrice
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&row, id).Error; err != nil {
+			return err
+		}
+		if row.Status != models.PriceScheduled {
+			return fmt.Errorf("price change %d is already %s", id, row.Status)
+		}
+		row.Status = models.PriceCancelled
+		return tx.Model(&row).Update("status", row.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// History returns every price entry for a product, newest first
+func (s *PriceService) History(ctx context.Context, productID uint) ([]models.ProductPrice, error) {
+	var rows []models.ProductPrice
+	err := s.DB.WithContext(ctx).
+		Where("product_id = ?", productID).
+		Order("effective_at DESC, id DESC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// ApplyDue applies all scheduled changes whose time has come. Rows are
+// claimed with SKIP LOCKED so several instances can run the scheduler.
+func (s *PriceService) ApplyDue(ctx context.Context) (int, error) {
+	applied := 0
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []models.ProductPrice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND effective_at <= ?", models.PriceScheduled, time.Now()).
+			Order("effective_at ASC, id ASC").
+			Find(&due).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, row := range due {
+			if err := tx.Model(&models.Product{}).Where("id = ?", row.ProductID).Update("price", row.Price).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&row).Updates(map[string]interface{}{
+				"status":     models.PriceApplied,
+				"applied_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if applied > 0 {
+		redis.DelPattern(ctx, "products:*")
+	}
+	return applied, nil
+}
+
+// RunScheduler applies due price changes every interval until ctx is done
+func (s *PriceService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ApplyDue(ctx)
+			if err != nil {
+				log.Printf("prices: scheduler error: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("prices: applied %d scheduled price change(s)", n)
+			}
+		}
+	}
+}
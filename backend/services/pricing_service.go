@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"swiggy-clone/backend/models"
+	"swiggy-clone/backend/pricing"
+)
+
+// PricingService loads restaurant rules and delivery locations and hands
+// them to the pricing package
+type PricingService struct {
+	DB *gorm.DB
+}
+
+// Quote prices the lines for a user. Pass the checkout transaction as db so
+// the bill is computed against the same rows that are being ordered.
+func (s *PricingService) Quote(db *gorm.DB, userID uint, lines []pricing.Line) (pricing.Bill, error) {
+	adminIDs := []uint{}
+	seen := map[uint]bool{}
+	for _, l := range lines {
+		if !seen[l.RestaurantID] {
+			seen[l.RestaurantID] = true
+			adminIDs = append(adminIDs, l.RestaurantID)
+		}
+	}
+
+	rules := map[uint]pricing.Rules{}
+	if len(adminIDs) > 0 {
+		var restaurants []models.Restaurant
+		if err := db.Where("admin_id IN ?", adminIDs).Find(&restaurants).Error; err != nil {
+			return pricing.Bill{}, fmt.Errorf("load restaurants: %w", err)
+		}
+		for i := range restaurants {
+			r, err := RulesFor(&restaurants[i])
+			if err != nil {
+				return pricing.Bill{}, err
+			}
+			rules[restaurants[i].AdminID] = r
+		}
+	}
+
+	var dest *pricing.Location
+	var user models.User
+	if err := db.Select("id", "delivery_lat", "delivery_lng").First(&user, userID).Error; err == nil &&
+		user.DeliveryLat != nil && user.DeliveryLng != nil {
+		dest = &pricing.Location{Lat: *user.DeliveryLat, Lng: *user.DeliveryLng}
+	}
+
+	return pricing.Compute(lines, func(adminID uint) pricing.Rules {
+		if r, ok := rules[adminID]; ok {
+			return r
+		}
+		return pricing.DefaultRules()
+	}, dest), nil
+}
+
+// RulesFor converts a restaurant row into pricing rules. Unreadable GST
+// rates are an error rather than no tax.
+func RulesFor(r *models.Restaurant) (pricing.Rules, error) {
+	rules := pricing.Rules{
+		GSTBps:              map[string]int64{},
+		DefaultGSTBps:       r.DefaultGSTBps,
+		PackagingFee:        r.PackagingFee,
+		DeliveryBaseFee:     r.DeliveryBaseFee,
+		DeliveryBaseKm:      r.DeliveryBaseKm,
+		DeliveryPerKm:       r.DeliveryPerKm,
+		SmallOrderThreshold: r.SmallOrderThreshold,
+		SmallOrderFee:       r.SmallOrderFee,
+		PlatformFee:         r.PlatformFee,
+	}
+	if len(r.GSTRates) > 0 {
+		if err := json.Unmarshal(r.GSTRates, &rules.GSTBps); err != nil {
+			return pricing.Rules{}, fmt.Errorf("restaurant %d: invalid GST rates: %w", r.AdminID, err)
+		}
+	}
+	if r.Lat != nil && r.Lng != nil {
+		rules.Origin = &pricing.Location{Lat: *r.Lat, Lng: *r.Lng}
+	}
+	return rules, nil
+}
+
+// Restaurant returns the admin's restaurant settings, or nil if none are saved
+func (s *PricingService) Restaurant(ctx context.Context, adminID uint) (*models.Restaurant, error) {
+	var r models.Restaurant
+	err := s.DB.WithContext(ctx).Where("admin_id = ?", adminID).First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// SaveRestaurant creates or replaces the admin's pricing settings. Whether
+// the restaurant is open is left alone; see SetOpen.
+func (s *PricingService) SaveRestaurant(ctx context.Context, r *models.Restaurant) error {
+	return s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "admin_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"name", "lat", "lng", "gst_rates", "default_gst_bps", "packaging_fee",
+			"delivery_base_fee", "delivery_base_km", "delivery_per_km",
+			"small_order_threshold", "small_order_fee", "platform_fee", "updated_at",
+		}),
+	}).Create(r).Error
+}
+
+// SetOpen opens or closes the admin's restaurant for new orders. Admins
+// without saved settings get a row with the default pricing rules.
+func (s *PricingService) SetOpen(ctx context.Context, adminID uint, open bool) (*models.Restaurant, error) {
+	r, err := s.Restaurant(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		d := pricing.DefaultRules()
+		r = &models.Restaurant{
+			AdminID:             adminID,
+			GSTRates:            []byte("{}"),
+			DefaultGSTBps:       d.DefaultGSTBps,
+			PackagingFee:        d.PackagingFee,
+			DeliveryBaseFee:     d.DeliveryBaseFee,
+			DeliveryBaseKm:      d.DeliveryBaseKm,
+			DeliveryPerKm:       d.DeliveryPerKm,
+			SmallOrderThreshold: d.SmallOrderThreshold,
+			SmallOrderFee:       d.SmallOrderFee,
+			PlatformFee:         d.PlatformFee,
+		}
+		if err := s.SaveRestaurant(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.DB.WithContext(ctx).Model(r).Update("is_open", open).Error; err != nil {
+		return nil, err
+	}
+	r.IsOpen = open
+	return r, nil
+}
+
+// ClosedRestaurants returns which of the given admins' restaurants are closed.
+// Admins without a restaurant row count as open.
+func (s *PricingService) ClosedRestaurants(db *gorm.DB, adminIDs []uint) (map[uint]bool, error) {
+	closed := map[uint]bool{}
+	if len(adminIDs) == 0 {
+		return closed, nil
+	}
+	var ids []uint
+	if err := db.Model(&models.Restaurant{}).
+		Where("admin_id IN ? AND NOT is_open", adminIDs).
+		Pluck("admin_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		closed[id] = true
+	}
+	return closed, nil
+}
+
+// SetDeliveryLocation stores where the user's orders go
+func (s *PricingService) SetDeliveryLocation(ctx context.Context, userID uint, loc pricing.Location) error {
+	if loc.Lat < -90 || loc.Lat > 90 || loc.Lng < -180 || loc.Lng > 180 {
+		return fmt.Errorf("invalid coordinates")
+	}
+	return s.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"delivery_lat": loc.Lat,
+		"delivery_lng": loc.Lng,
+	}).Error
+}
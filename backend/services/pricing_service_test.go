@@ -0,0 +1,48 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/datatypes"
+
+	"swiggy-clone/backend/models"
+)
+
+func TestRulesFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		gstRates string
+		want     map[string]int64
+		wantErr  bool
+	}{
+		{"no category rates", "", map[string]int64{}, false},
+		{"category rates", `{"beverages":1800,"packaged":1200}`, map[string]int64{"beverages": 1800, "packaged": 1200}, false},
+		{"corrupt rates", `{"beverages":`, nil, true},
+		{"rates of the wrong type", `{"beverages":"high"}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &models.Restaurant{AdminID: 7, DefaultGSTBps: 500}
+			if tt.gstRates != "" {
+				r.GSTRates = datatypes.JSON(tt.gstRates)
+			}
+			rules, err := RulesFor(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("RulesFor succeeded with GST rates %s", tt.gstRates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RulesFor: %v", err)
+			}
+			if !reflect.DeepEqual(rules.GSTBps, tt.want) {
+				t.Errorf("GST rates %v, want %v", rules.GSTBps, tt.want)
+			}
+			if rules.DefaultGSTBps != 500 {
+				t.Errorf("default GST %d, want 500", rules.DefaultGSTBps)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail scales src down so its longest side is at most maxSide pixels,
+// keeping the aspect ratio. Each output pixel is the average of the source
+// pixels it covers (box filter), which is good enough for product photos and
+// keeps us on the standard library. Images already small enough are copied.
+func Thumbnail(src image.Image, maxSide int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	dw, dh := sw, sh
+	if sw > maxSide || sh > maxSide {
+		if sw >= sh {
+			dw = maxSide
+			dh = max(1, sh*maxSide/sw)
+		} else {
+			dh = maxSide
+			dw = max(1, sw*maxSide/sh)
+		}
+	}
+
+	// Normalise to RGBA so we can read pixels straight from the slice
+	rgba := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	if dw == sw && dh == sh {
+		return rgba
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0 := dy * sh / dh
+		y1 := max(y0+1, (dy+1)*sh/dh)
+		for dx := 0; dx < dw; dx++ {
+			x0 := dx * sw / dw
+			x1 := max(x0+1, (dx+1)*sw/dw)
+
+			var r, g, bl, a, n uint32
+			for y := y0; y < y1; y++ {
+				off := rgba.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					r += uint32(rgba.Pix[off])
+					g += uint32(rgba.Pix[off+1])
+					bl += uint32(rgba.Pix[off+2])
+					a += uint32(rgba.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -44,7 +44,7 @@ export const GET_PRODUCTS_COUNT = gql`
 export const CREATE_PRODUCT = gql`
   mutation CreateProduct(
     $name: String!
-    $price: Float!
+    $price: Money!
     $stock: Int!
     $quantity: String
     $image: String
@@ -72,7 +72,7 @@ export const UPDATE_PRODUCT = gql`
   mutation UpdateProduct(
     $id: ID!
     $name: String
-    $price: Float
+    $price: Money
     $stock: Int
     $quantity: String
     $image: String
@@ -106,9 +106,9 @@ export default function OrdersPage() {
                     </span>
                     <span
                       className={`px-3 py-1 text-sm font-semibold rounded-full ${
-                        order.status === "SUCCESS"
+                        order.status === "DELIVERED"
                           ? "bg-green-100 text-green-700"
-                          : order.status === "PENDING"
+                          : order.status === "PLACED"
                           ? "bg-yellow-100 text-yellow-700"
                           : "bg-gray-100 text-gray-600"
                       }`}